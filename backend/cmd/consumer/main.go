@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/segmentio/kafka-go"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/analytics"
 )
 
 func main() {
@@ -25,6 +27,16 @@ func main() {
 			time.Sleep(time.Second)
 			continue
 		}
-		log.Printf("event: %s", string(m.Value))
+		event, err := analytics.Decode(m.Value)
+		if err != nil {
+			log.Printf("skipping invalid event: %v: %s", err, string(m.Value))
+			continue
+		}
+		switch p := event.Payload.(type) {
+		case analytics.Finished:
+			log.Printf("game %s finished by %s after %d moves, winner %q", event.GameID, p.Termination, len(p.Moves), p.Winner)
+		default:
+			log.Printf("%s game=%s payload=%+v", event.Type, event.GameID, p)
+		}
 	}
 }
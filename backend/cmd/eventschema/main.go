@@ -0,0 +1,31 @@
+// Command eventschema writes a JSON Schema document for every analytics event type. It runs
+// through go generate in internal/analytics; commit its output with any change to the events.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/analytics"
+)
+
+func main() {
+	out := flag.String("out", "schemas/events", "directory to write the schemas to")
+	flag.Parse()
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	for _, t := range analytics.EventTypes() {
+		doc, err := analytics.Schema(t)
+		if err != nil {
+			log.Fatal(err)
+		}
+		path := filepath.Join(*out, string(t)+".schema.json")
+		if err := os.WriteFile(path, append(doc, '\n'), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/config"
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/storage"
+)
+
+// migrate applies pending schema migrations to the configured STORAGE, or with -status only
+// checks, without writing anything, whether the schema matches this binary.
+func main() {
+	status := flag.Bool("status", false, "report whether the schema is current without migrating")
+	flag.Parse()
+
+	cfg := config.Load()
+	if *status {
+		err := storage.SchemaStatus(context.Background(), cfg.Storage, cfg.PostgresURL, cfg.SQLitePath)
+		switch {
+		case errors.Is(err, storage.ErrUnversioned):
+			log.Fatalf("%s schema is unversioned: no migrations have been applied", cfg.Storage)
+		case err != nil:
+			log.Fatalf("%s storage: %v", cfg.Storage, err)
+		}
+		log.Printf("%s schema is up to date", cfg.Storage)
+		return
+	}
+	repo, err := storage.Open(context.Background(), cfg.Storage, cfg.PostgresURL, cfg.SQLitePath, true)
+	if err != nil {
+		log.Fatalf("%s storage: %v", cfg.Storage, err)
+	}
+	defer repo.Close()
+	log.Printf("%s schema is up to date", cfg.Storage)
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/rishirajmaheshwari/4-in-a-row/internal/analytics"
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/auth"
 	"github.com/rishirajmaheshwari/4-in-a-row/internal/config"
 	"github.com/rishirajmaheshwari/4-in-a-row/internal/game"
 	"github.com/rishirajmaheshwari/4-in-a-row/internal/server"
@@ -19,22 +22,54 @@ func main() {
 	cfg := config.Load()
 	ctx := context.Background()
 
-	repo, err := storage.NewRepository(ctx, cfg.PostgresURL)
+	repo, err := storage.Open(ctx, cfg.Storage, cfg.PostgresURL, cfg.SQLitePath, cfg.MigrateOnStart)
 	if err != nil {
-		log.Fatalf("postgres: %v", err)
+		log.Fatalf("%s storage: %v", cfg.Storage, err)
 	}
 	defer repo.Close()
 
-	var producer *analytics.Producer
-	if len(cfg.KafkaBrokers) > 0 {
-		producer = analytics.NewProducer(cfg.KafkaBrokers, "game-analytics")
-		defer producer.Close()
+	sink, err := analytics.Open(analytics.SinkConfig{
+		Sinks:        cfg.AnalyticsSinks,
+		KafkaBrokers: cfg.KafkaBrokers,
+		KafkaTopic:   "game-analytics",
+		Kafka: analytics.Options{
+			QueueSize:    cfg.AnalyticsQueueSize,
+			BatchSize:    cfg.AnalyticsBatchSize,
+			BatchTimeout: time.Duration(cfg.AnalyticsBatchMs) * time.Millisecond,
+			Overflow:     analytics.Overflow(cfg.AnalyticsOverflow),
+		},
+		FilePath:     cfg.AnalyticsFile,
+		FileMaxBytes: int64(cfg.AnalyticsFileMaxMB) << 20,
+		FileKeep:     cfg.AnalyticsFileKeep,
+	})
+	if err != nil {
+		log.Fatalf("analytics: %v", err)
+	}
+	var relay *analytics.Relay
+	relayCtx, stopRelay := context.WithCancel(ctx)
+	defer stopRelay()
+	if sink != nil {
+		relay = analytics.NewRelay(repo, sink)
+		go relay.Run(relayCtx)
 	} else {
-		log.Println("analytics disabled: no KAFKA_BROKERS configured")
+		log.Println("analytics disabled: no ANALYTICS_SINKS or KAFKA_BROKERS configured")
+	}
+
+	manager := game.NewManager(game.RatingWindow{Base: cfg.MatchRatingWindow, PerSecond: cfg.MatchWindowGrowth})
+	secret := []byte(cfg.AuthSecret)
+	if len(secret) == 0 {
+		log.Println("AUTH_SECRET not set: using a random key, sessions will not survive a restart")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("auth secret: %v", err)
+		}
 	}
+	signer := auth.NewSigner(secret, time.Duration(cfg.SessionTTLHours)*time.Hour)
 
-	manager := game.NewManager()
-	srv := server.New(cfg, manager, repo, producer)
+	srv := server.New(cfg, manager, repo, sink, relay, signer)
+	if err := srv.RestoreGames(ctx); err != nil {
+		log.Fatalf("restore games: %v", err)
+	}
 
 	httpServer := &http.Server{Addr: ":" + cfg.Port, Handler: srv.Routes()}
 
@@ -50,4 +85,10 @@ func main() {
 	<-quit
 	log.Println("shutting down")
 	_ = httpServer.Shutdown(context.Background())
+	if sink != nil {
+		stopRelay()
+		if err := sink.Close(); err != nil {
+			log.Printf("analytics flush: %v", err)
+		}
+	}
 }
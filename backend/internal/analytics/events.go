@@ -0,0 +1,286 @@
+package analytics
+
+//go:generate go run ../../cmd/eventschema -out ../../schemas/events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// SchemaVersion is the version of the event contract this binary emits. Adding an optional
+// field keeps the version; renaming, removing or retyping a field bumps it.
+const SchemaVersion = 1
+
+var (
+	ErrUnknownEventType   = errors.New("unknown event type")
+	ErrUnsupportedVersion = errors.New("unsupported event schema version")
+)
+
+// EventType names an event kind; each has its own payload struct.
+type EventType string
+
+const (
+	EventQueueEntered EventType = "queue_entered"
+	EventQueueLeft    EventType = "queue_left"
+	EventMatchMade    EventType = "match_made"
+	EventJoined       EventType = "joined"
+	EventMove         EventType = "move"
+	EventBotMove      EventType = "bot_move"
+	EventDisconnected EventType = "disconnected"
+	EventReconnected  EventType = "reconnected"
+	EventForfeit      EventType = "forfeit"
+	EventFinished     EventType = "finished"
+	EventRematch      EventType = "rematch"
+	EventError        EventType = "error"
+)
+
+// payloadTypes maps every event type to a constructor for its payload. It is the registry
+// decoding, validation and schema generation all work from.
+var payloadTypes = map[EventType]func() Payload{
+	EventQueueEntered: func() Payload { return &QueueEntered{} },
+	EventQueueLeft:    func() Payload { return &QueueLeft{} },
+	EventMatchMade:    func() Payload { return &MatchMade{} },
+	EventJoined:       func() Payload { return &Joined{} },
+	EventMove:         func() Payload { return &MovePlayed{} },
+	EventBotMove:      func() Payload { return &BotMove{} },
+	EventDisconnected: func() Payload { return &Disconnected{} },
+	EventReconnected:  func() Payload { return &Reconnected{} },
+	EventForfeit:      func() Payload { return &Forfeit{} },
+	EventFinished:     func() Payload { return &Finished{} },
+	EventRematch:      func() Payload { return &Rematch{} },
+	EventError:        func() Payload { return &ClientError{} },
+}
+
+// gameless lists the event types that can happen before a player has a game; their gameId may
+// be empty. Every other event is keyed by the game it belongs to.
+var gameless = map[EventType]bool{
+	EventQueueEntered: true,
+	EventQueueLeft:    true, // abandoned
+	EventError:        true,
+}
+
+// Payload is the type-specific part of an event. Field constraints are declared with schema
+// tags, which drive both Validate and the generated JSON Schema:
+//
+//	schema:"nonempty"         the string must not be empty
+//	schema:"min=0"            the number must be at least 0
+//	schema:"enum=drop|pop"    the string must be one of the listed values
+type Payload interface {
+	EventType() EventType
+}
+
+// QueueEntered is emitted when a player joins the public matchmaking queue.
+type QueueEntered struct {
+	Player string  `json:"player" schema:"nonempty"`
+	Rating float64 `json:"rating" schema:"min=0"`
+}
+
+// QueueLeft is emitted when a queued player leaves the queue: for a game "matched" with a
+// human or against the "bot" when nobody was found in time, or "abandoned" when they
+// disconnected first, without a game.
+type QueueLeft struct {
+	Player string `json:"player" schema:"nonempty"`
+	WaitMs int64  `json:"waitMs" schema:"min=0"`
+	Reason string `json:"reason" schema:"enum=matched|bot|abandoned"`
+}
+
+// MatchMade is emitted for each queued player once their game is set up.
+type MatchMade struct {
+	Player      string `json:"player" schema:"nonempty"`
+	Opponent    string `json:"opponent" schema:"nonempty"`
+	WaitMs      int64  `json:"waitMs" schema:"min=0"`
+	BotFallback bool   `json:"botFallback"` // no human was found and the bot stepped in
+}
+
+// Joined is emitted when a player takes their seat in a game.
+type Joined struct {
+	Player string `json:"player" schema:"nonempty"`
+}
+
+// MovePlayed is emitted for every move, by a player or the bot.
+type MovePlayed struct {
+	Player  string `json:"player" schema:"nonempty"`
+	Number  int    `json:"number" schema:"min=1"` // 1 for the game's first move
+	Column  int    `json:"column" schema:"min=0"`
+	Kind    string `json:"kind" schema:"enum=drop|pop"`
+	ThinkMs int64  `json:"thinkMs" schema:"min=0"` // time since the previous move, or since the game started
+}
+
+// BotMove is emitted for every move the bot plays, alongside its move event.
+type BotMove struct {
+	Column     int    `json:"column" schema:"min=0"`
+	Kind       string `json:"kind" schema:"enum=drop|pop"`
+	Difficulty string `json:"difficulty" schema:"enum=beginner|casual|expert|max"`
+}
+
+// Disconnected is emitted when a player's connection to a game in progress drops.
+type Disconnected struct {
+	Player string `json:"player" schema:"nonempty"`
+}
+
+// Reconnected is emitted when a player connects again to a game they were already in.
+type Reconnected struct {
+	Player string `json:"player" schema:"nonempty"`
+}
+
+// Forfeit is emitted when a disconnected player does not come back within the grace period.
+type Forfeit struct {
+	Player       string `json:"player" schema:"nonempty"` // the player who forfeited
+	Winner       string `json:"winner" schema:"nonempty"`
+	GraceSeconds int    `json:"graceSeconds" schema:"min=0"`
+}
+
+// Finished is emitted once per game, through the outbox, when it ends.
+type Finished struct {
+	Winner      string `json:"winner"` // empty for a draw
+	Termination string `json:"termination" schema:"enum=connect|draw|forfeit|timeout|resign|agreement"`
+	Moves       []Move `json:"moves"`
+}
+
+// Move is one move of a finished game.
+type Move struct {
+	Column int    `json:"column" schema:"min=0"`
+	Kind   string `json:"kind" schema:"enum=drop|pop"`
+	By     string `json:"by" schema:"nonempty"`
+}
+
+// Rematch is emitted for the new game when both players agree to a rematch.
+type Rematch struct {
+	PreviousGameID string `json:"previousGameId" schema:"nonempty"`
+}
+
+// ClientError is emitted when a player's request fails, e.g. an illegal move. Action is what
+// they tried: "join", or the type of the WebSocket message.
+type ClientError struct {
+	Player  string `json:"player" schema:"nonempty"`
+	Action  string `json:"action" schema:"nonempty"`
+	Message string `json:"message" schema:"nonempty"`
+}
+
+func (QueueEntered) EventType() EventType { return EventQueueEntered }
+func (QueueLeft) EventType() EventType    { return EventQueueLeft }
+func (MatchMade) EventType() EventType    { return EventMatchMade }
+func (Joined) EventType() EventType       { return EventJoined }
+func (MovePlayed) EventType() EventType   { return EventMove }
+func (BotMove) EventType() EventType      { return EventBotMove }
+func (Disconnected) EventType() EventType { return EventDisconnected }
+func (Reconnected) EventType() EventType  { return EventReconnected }
+func (Forfeit) EventType() EventType      { return EventForfeit }
+func (Finished) EventType() EventType     { return EventFinished }
+func (Rematch) EventType() EventType      { return EventRematch }
+func (ClientError) EventType() EventType  { return EventError }
+
+// Event is the envelope every analytics event is sent in. GameID is empty only for gameless types.
+type Event struct {
+	Version    int       `json:"version"`
+	Type       EventType `json:"type"`
+	GameID     string    `json:"gameId"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Payload    Payload   `json:"payload"`
+}
+
+// NewEvent wraps the payload in an envelope stamped with the current version and time.
+func NewEvent(gameID string, payload Payload) Event {
+	return Event{
+		Version:    SchemaVersion,
+		Type:       payload.EventType(),
+		GameID:     gameID,
+		OccurredAt: time.Now().UTC(),
+		Payload:    payload,
+	}
+}
+
+// Validate checks the envelope and the payload's schema tags.
+func (e Event) Validate() error {
+	if e.Version != SchemaVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, e.Version)
+	}
+	if _, ok := payloadTypes[e.Type]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownEventType, e.Type)
+	}
+	if e.Payload == nil || e.Payload.EventType() != e.Type {
+		return fmt.Errorf("%s event: payload does not match type", e.Type)
+	}
+	if e.GameID == "" && !gameless[e.Type] {
+		return fmt.Errorf("%s event: gameId is empty", e.Type)
+	}
+	if e.OccurredAt.IsZero() {
+		return fmt.Errorf("%s event: occurredAt is missing", e.Type)
+	}
+	if err := validateValue(e.Payload, "payload"); err != nil {
+		return fmt.Errorf("%s event: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Encode validates the event and returns its JSON. Sinks only ever write encoded events.
+func Encode(e Event) ([]byte, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+// legacyVersion is the shape of events written before the envelope carried a version, such as
+// rows still waiting in the outbox from an older binary. It is version 1 apart from moves
+// recorded without a kind, which were drops.
+const legacyVersion = 1
+
+// UnmarshalJSON decodes the payload into the struct registered for the event's type, so
+// consumers get the same types the server emits. An envelope without a version is read as
+// legacyVersion.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var envelope struct {
+		Version    *int            `json:"version"`
+		Type       EventType       `json:"type"`
+		GameID     string          `json:"gameId"`
+		OccurredAt time.Time       `json:"occurredAt"`
+		Payload    json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return err
+	}
+	version := legacyVersion
+	if envelope.Version != nil {
+		version = *envelope.Version
+	}
+	if version != SchemaVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	newPayload, ok := payloadTypes[envelope.Type]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownEventType, envelope.Type)
+	}
+	payload := newPayload()
+	if err := json.Unmarshal(envelope.Payload, payload); err != nil {
+		return fmt.Errorf("%s payload: %w", envelope.Type, err)
+	}
+	if finished, ok := payload.(*Finished); ok && envelope.Version == nil {
+		for i := range finished.Moves {
+			if finished.Moves[i].Kind == "" {
+				finished.Moves[i].Kind = "drop"
+			}
+		}
+	}
+	*e = Event{
+		Version:    version,
+		Type:       envelope.Type,
+		GameID:     envelope.GameID,
+		OccurredAt: envelope.OccurredAt,
+		// Hand back the struct itself, as emitted, rather than the pointer it was decoded into.
+		Payload: reflect.ValueOf(payload).Elem().Interface().(Payload),
+	}
+	return nil
+}
+
+// Decode parses and validates one encoded event.
+func Decode(data []byte) (Event, error) {
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Event{}, err
+	}
+	return e, e.Validate()
+}
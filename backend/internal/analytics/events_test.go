@@ -0,0 +1,48 @@
+package analytics
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeLegacyOutboxRow(t *testing.T) {
+	// A finished event as the outbox stored it before envelopes were versioned.
+	row := `{"type":"finished","gameId":"g1","payload":{"winner":"ann","termination":"connect",` +
+		`"moves":[{"column":3,"by":"ann"},{"column":2,"kind":"pop","by":"bot"}]},"occurredAt":"2026-01-01T10:00:00Z"}`
+	e, err := Decode([]byte(row))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Finished{Winner: "ann", Termination: "connect", Moves: []Move{
+		{Column: 3, Kind: "drop", By: "ann"},
+		{Column: 2, Kind: "pop", By: "bot"},
+	}}
+	if e.Version != SchemaVersion || e.GameID != "g1" || !reflect.DeepEqual(e.Payload, want) {
+		t.Errorf("got %+v", e)
+	}
+}
+
+func TestDecodeRejectsOtherVersions(t *testing.T) {
+	for _, version := range []string{"0", "2"} {
+		row := `{"version":` + version + `,"type":"joined","gameId":"g1","payload":{"player":"ann"},"occurredAt":"2026-01-01T10:00:00Z"}`
+		if _, err := Decode([]byte(row)); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("version %s: got %v", version, err)
+		}
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	e := NewEvent("g1", BotMove{Column: 4, Kind: "drop", Difficulty: "expert"})
+	data, err := Encode(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Payload != e.Payload || got.Version != e.Version || !got.OccurredAt.Equal(e.OccurredAt) {
+		t.Errorf("got %+v, want %+v", got, e)
+	}
+}
@@ -0,0 +1,169 @@
+package analytics
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriterSink writes each event as one line of JSON.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// NewStdoutSink writes events to standard output, for local development.
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+func (s *WriterSink) Emit(ctx context.Context, event Event) error {
+	line, err := Encode(event)
+	if err != nil {
+		return err
+	}
+	return s.Publish(ctx, line)
+}
+
+func (s *WriterSink) Publish(ctx context.Context, encoded ...[]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, line := range encoded {
+		if _, err := s.w.Write(withNewline(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// withNewline returns a copy of line ending in a newline. Capping the slice makes append copy
+// instead of writing into the caller's array.
+func withNewline(line []byte) []byte {
+	return append(line[:len(line):len(line)], '\n')
+}
+
+// FileSink appends events as newline-delimited JSON to a file. Once the file reaches maxBytes
+// it is renamed with a timestamp suffix and a new one started; only the newest keep rotated
+// files are kept.
+type FileSink struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	keep     int
+	file     *os.File
+	size     int64
+}
+
+func NewFileSink(path string, maxBytes int64, keep int) (*FileSink, error) {
+	s := &FileSink{path: path, maxBytes: maxBytes, keep: keep}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+func (s *FileSink) Emit(ctx context.Context, event Event) error {
+	line, err := Encode(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeLocked(line)
+}
+
+// Publish appends the events and syncs the file, so they survive a crash once it returns.
+func (s *FileSink) Publish(ctx context.Context, encoded ...[]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, line := range encoded {
+		if err := s.writeLocked(line); err != nil {
+			return err
+		}
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) writeLocked(line []byte) error {
+	if s.file == nil {
+		return ErrSinkClosed
+	}
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(line))+1 > s.maxBytes {
+		if err := s.rotateLocked(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(withNewline(line))
+	s.size += int64(n)
+	return err
+}
+
+func (s *FileSink) rotateLocked() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+	ext := filepath.Ext(s.path)
+	base := strings.TrimSuffix(s.path, ext)
+	rotated := base + "-" + time.Now().UTC().Format("20060102T150405.000000000") + ext
+	if err := os.Rename(s.path, rotated); err != nil {
+		return err
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	if s.keep <= 0 {
+		return nil
+	}
+	// The timestamp format sorts chronologically, so the oldest files come first.
+	old, err := filepath.Glob(base + "-*" + ext)
+	if err != nil {
+		return err
+	}
+	sort.Strings(old)
+	for len(old) > s.keep {
+		if err := os.Remove(old[0]); err != nil {
+			return err
+		}
+		old = old[1:]
+	}
+	return nil
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
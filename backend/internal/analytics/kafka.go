@@ -0,0 +1,209 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+var (
+	ErrQueueFull  = errors.New("analytics queue full")
+	ErrSinkClosed = errors.New("analytics sink closed")
+)
+
+// Overflow is what Emit does when the queue is full.
+type Overflow string
+
+const (
+	OverflowDrop  Overflow = "drop"  // discard the event and count it as dropped
+	OverflowBlock Overflow = "block" // wait for room, or until the caller's context ends
+)
+
+// Options tune the background batching. Zero values fall back to the defaults below.
+type Options struct {
+	QueueSize    int
+	BatchSize    int
+	BatchTimeout time.Duration // longest an event waits for its batch to fill
+	Overflow     Overflow
+}
+
+const (
+	defaultQueueSize    = 1000
+	defaultBatchSize    = 100
+	defaultBatchTimeout = 500 * time.Millisecond
+)
+
+// Stats counts events that never reached Kafka.
+type Stats struct {
+	Dropped int64 `json:"dropped"` // rejected because the queue was full
+	Failed  int64 `json:"failed"`  // lost in a batch Kafka refused
+}
+
+// KafkaSink queues events in memory and writes them to Kafka in batches from a background
+// goroutine, so Emit never waits on the network.
+type KafkaSink struct {
+	writer   *kafka.Writer
+	overflow Overflow
+	queue    chan kafka.Message
+	done     chan struct{}
+
+	mu     sync.RWMutex // held for reading while enqueuing, for writing while closing
+	closed bool
+
+	dropped atomic.Int64
+	failed  atomic.Int64
+}
+
+func NewKafkaSink(brokers []string, topic string, opts Options) *KafkaSink {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.BatchTimeout <= 0 {
+		opts.BatchTimeout = defaultBatchTimeout
+	}
+	if opts.Overflow != OverflowBlock {
+		opts.Overflow = OverflowDrop
+	}
+	k := &KafkaSink{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			RequiredAcks: kafka.RequireAll,
+			// Events are keyed by game ID, so each game's events land in one partition in order;
+			// events without a game are spread round-robin.
+			Balancer: &kafka.Hash{},
+			// Batches are assembled before they reach the writer; don't let it wait for more.
+			BatchSize:    opts.BatchSize,
+			BatchTimeout: 10 * time.Millisecond,
+		},
+		overflow: opts.Overflow,
+		queue:    make(chan kafka.Message, opts.QueueSize),
+		done:     make(chan struct{}),
+	}
+	go k.run(opts.BatchSize, opts.BatchTimeout)
+	return k
+}
+
+// Emit queues the event for the next batch. With OverflowDrop a full queue drops the event and
+// returns ErrQueueFull; with OverflowBlock Emit waits for room until ctx ends.
+func (k *KafkaSink) Emit(ctx context.Context, event Event) error {
+	payload, err := Encode(event)
+	if err != nil {
+		return err
+	}
+	msg := kafka.Message{Key: messageKey(event.GameID), Value: payload}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.closed {
+		return ErrSinkClosed
+	}
+	if k.overflow == OverflowBlock {
+		select {
+		case k.queue <- msg:
+			return nil
+		case <-ctx.Done():
+			k.dropped.Add(1)
+			return ctx.Err()
+		}
+	}
+	select {
+	case k.queue <- msg:
+		return nil
+	default:
+		k.dropped.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// Publish writes events that are already encoded, such as those read back from the outbox.
+// Unlike Emit it bypasses the queue and returns once Kafka has acknowledged the write. Each
+// event is keyed by the gameId in its envelope.
+func (k *KafkaSink) Publish(ctx context.Context, encoded ...[]byte) error {
+	messages := make([]kafka.Message, len(encoded))
+	for i, value := range encoded {
+		var envelope struct {
+			GameID string `json:"gameId"`
+		}
+		_ = json.Unmarshal(value, &envelope)
+		messages[i] = kafka.Message{Key: messageKey(envelope.GameID), Value: value}
+	}
+	return k.writer.WriteMessages(ctx, messages...)
+}
+
+// messageKey partitions by game; a nil key lets the balancer pick any partition.
+func messageKey(gameID string) []byte {
+	if gameID == "" {
+		return nil
+	}
+	return []byte(gameID)
+}
+
+// run drains the queue, writing a batch when it reaches batchSize or its oldest event has
+// waited batchTimeout. It flushes what is left once the queue is closed.
+func (k *KafkaSink) run(batchSize int, batchTimeout time.Duration) {
+	defer close(k.done)
+	batch := make([]kafka.Message, 0, batchSize)
+	timer := time.NewTimer(batchTimeout)
+	timer.Stop()
+	flush := func() {
+		timer.Stop()
+		if len(batch) == 0 {
+			return
+		}
+		if err := k.writer.WriteMessages(context.Background(), batch...); err != nil {
+			k.failed.Add(int64(len(batch)))
+			log.Printf("analytics: dropped batch of %d events: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case msg, ok := <-k.queue:
+			if !ok {
+				flush()
+				return
+			}
+			if len(batch) == 0 {
+				timer.Reset(batchTimeout)
+			}
+			batch = append(batch, msg)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+// Stats returns how many events have been dropped or failed so far.
+func (k *KafkaSink) Stats() Stats {
+	return Stats{Dropped: k.dropped.Load(), Failed: k.failed.Load()}
+}
+
+// Close stops accepting events, flushes the queue to Kafka, closes the writer and logs how
+// many events were lost.
+func (k *KafkaSink) Close() error {
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		return nil
+	}
+	k.closed = true
+	close(k.queue)
+	k.mu.Unlock()
+	<-k.done
+	stats := k.Stats()
+	log.Printf("analytics: kafka sink dropped %d events, %d failed", stats.Dropped, stats.Failed)
+	return k.writer.Close()
+}
@@ -0,0 +1,96 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/storage"
+)
+
+const (
+	relayBatchSize    = 100
+	relayPollInterval = 5 * time.Second
+	relayMinBackoff   = time.Second
+	relayMaxBackoff   = time.Minute
+)
+
+// Outbox holds events that must survive a sink outage; storage.Store implements it.
+type Outbox interface {
+	PendingEvents(ctx context.Context, limit int) ([]storage.OutboxEvent, error)
+	DeleteEvents(ctx context.Context, ids []int64) error
+}
+
+// Relay publishes outbox events to a sink and deletes them once the sink has accepted them.
+// An event is only deleted after a successful write, so delivery is at least once: a crash
+// between the two republishes the batch.
+type Relay struct {
+	outbox Outbox
+	sink   EventSink
+	wake   chan struct{}
+}
+
+func NewRelay(outbox Outbox, sink EventSink) *Relay {
+	return &Relay{outbox: outbox, sink: sink, wake: make(chan struct{}, 1)}
+}
+
+// Notify tells the relay new events were written, so it publishes them without waiting for
+// the next poll. It never blocks.
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes pending events until ctx is cancelled. Failures are retried with exponential
+// backoff; events stay in the outbox meanwhile.
+func (r *Relay) Run(ctx context.Context) {
+	backoff := relayMinBackoff
+	for {
+		wait := relayPollInterval
+		if err := r.drain(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("outbox relay: %v (retrying in %s)", err, backoff)
+			wait = backoff
+			backoff = min(backoff*2, relayMaxBackoff)
+		} else {
+			backoff = relayMinBackoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-r.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// drain publishes batches until the outbox is empty.
+func (r *Relay) drain(ctx context.Context) error {
+	for {
+		events, err := r.outbox.PendingEvents(ctx, relayBatchSize)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		encoded := make([][]byte, len(events))
+		ids := make([]int64, len(events))
+		for i, e := range events {
+			encoded[i], ids[i] = e.Event, e.ID
+		}
+		if err := r.sink.Publish(ctx, encoded...); err != nil {
+			return err
+		}
+		if err := r.outbox.DeleteEvents(ctx, ids); err != nil {
+			return err
+		}
+		if len(events) < relayBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventTypes lists every event type, sorted.
+func EventTypes() []EventType {
+	types := make([]EventType, 0, len(payloadTypes))
+	for t := range payloadTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Schema returns the JSON Schema document describing events of type t, envelope included.
+func Schema(t EventType) ([]byte, error) {
+	newPayload, ok := payloadTypes[t]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEventType, t)
+	}
+	gameID := map[string]any{"type": "string", "minLength": 1}
+	if gameless[t] {
+		gameID = map[string]any{"type": "string", "description": "empty when the player has no game"}
+	}
+	doc := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       string(t) + " event",
+		"type":        "object",
+		"required":    []string{"version", "type", "gameId", "occurredAt", "payload"},
+		"description": fmt.Sprintf("Analytics event %q, schema version %d.", t, SchemaVersion),
+		"properties": map[string]any{
+			"version":    map[string]any{"const": SchemaVersion},
+			"type":       map[string]any{"const": string(t)},
+			"gameId":     gameID,
+			"occurredAt": map[string]any{"type": "string", "format": "date-time"},
+			"payload":    typeSchema(reflect.TypeOf(newPayload()).Elem()),
+		},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func typeSchema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	case t.Kind() == reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		for _, f := range structFields(t) {
+			prop := typeSchema(f.Type)
+			if f.nonEmpty {
+				prop["minLength"] = 1
+			}
+			if f.min != nil {
+				prop["minimum"] = *f.min
+			}
+			if f.enum != nil {
+				prop["enum"] = f.enum
+			}
+			properties[f.name] = prop
+			if !f.omitEmpty {
+				required = append(required, f.name)
+			}
+		}
+		return map[string]any{"type": "object", "properties": properties, "required": required}
+	}
+	panic("analytics: no JSON Schema for " + t.String())
+}
+
+// field is a struct field as seen through its json and schema tags.
+type field struct {
+	reflect.StructField
+	name      string
+	omitEmpty bool
+	nonEmpty  bool
+	min       *float64
+	enum      []string
+}
+
+func structFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		f := field{StructField: sf, name: name, omitEmpty: slices.Contains(strings.Split(opts, ","), "omitempty")}
+		for _, rule := range strings.Split(sf.Tag.Get("schema"), ",") {
+			key, value, _ := strings.Cut(rule, "=")
+			switch key {
+			case "nonempty":
+				f.nonEmpty = true
+			case "min":
+				n, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					panic(fmt.Sprintf("analytics: bad min in %s.%s", t.Name(), sf.Name))
+				}
+				f.min = &n
+			case "enum":
+				f.enum = strings.Split(value, "|")
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// validateValue checks v against the schema tags of its fields, recursing into structs and slices.
+func validateValue(v any, path string) error {
+	return validateReflect(reflect.ValueOf(v), path)
+}
+
+func validateReflect(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateReflect(v.Elem(), path)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateReflect(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return nil
+		}
+		for _, f := range structFields(v.Type()) {
+			fv := v.FieldByIndex(f.Index)
+			fpath := path + "." + f.name
+			if f.nonEmpty && fv.Kind() == reflect.String && fv.Len() == 0 {
+				return fmt.Errorf("%s is empty", fpath)
+			}
+			if f.min != nil {
+				var n float64
+				switch {
+				case fv.CanInt():
+					n = float64(fv.Int())
+				case fv.CanFloat():
+					n = fv.Float()
+				case fv.CanUint():
+					n = float64(fv.Uint())
+				}
+				if n < *f.min {
+					return fmt.Errorf("%s is %v, below the minimum %v", fpath, n, *f.min)
+				}
+			}
+			if f.enum != nil && !slices.Contains(f.enum, fv.String()) {
+				return fmt.Errorf("%s is %q, want one of %s", fpath, fv.String(), strings.Join(f.enum, ", "))
+			}
+			if err := validateReflect(fv, fpath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
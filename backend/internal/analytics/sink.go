@@ -0,0 +1,125 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// EventSink is somewhere analytics events go.
+type EventSink interface {
+	// Emit validates and records the event, possibly later; it must not block the caller on I/O
+	// for long.
+	Emit(ctx context.Context, event Event) error
+	// Publish writes already-encoded events and returns only once they are stored, so the
+	// outbox relay can delete them.
+	Publish(ctx context.Context, encoded ...[]byte) error
+	// Close flushes anything buffered.
+	Close() error
+}
+
+var (
+	_ EventSink = (*KafkaSink)(nil)
+	_ EventSink = (*FileSink)(nil)
+	_ EventSink = (*WriterSink)(nil)
+	_ EventSink = Fanout(nil)
+)
+
+// SinkConfig names the sinks Open builds and configures each of them.
+type SinkConfig struct {
+	Sinks []string // any of "kafka", "file" and "stdout"
+
+	KafkaBrokers []string
+	KafkaTopic   string
+	Kafka        Options
+
+	FilePath     string
+	FileMaxBytes int64 // rotate once the file reaches this size; 0 never rotates
+	FileKeep     int   // rotated files to keep; 0 keeps all
+}
+
+// Open builds the configured sinks, fanning out when there is more than one. It returns a nil
+// sink when none is configured.
+func Open(cfg SinkConfig) (EventSink, error) {
+	var sinks Fanout
+	for _, name := range cfg.Sinks {
+		switch name {
+		case "kafka":
+			if len(cfg.KafkaBrokers) == 0 {
+				sinks.Close()
+				return nil, errors.New("kafka sink needs KAFKA_BROKERS")
+			}
+			sinks = append(sinks, NewKafkaSink(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.Kafka))
+		case "file":
+			sink, err := NewFileSink(cfg.FilePath, cfg.FileMaxBytes, cfg.FileKeep)
+			if err != nil {
+				sinks.Close()
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case "stdout":
+			sinks = append(sinks, NewStdoutSink())
+		default:
+			sinks.Close()
+			return nil, fmt.Errorf("unknown analytics sink %q: use kafka, file or stdout", name)
+		}
+	}
+	switch len(sinks) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sinks[0], nil
+	}
+	return sinks, nil
+}
+
+// StatsOf returns the delivery stats of sink, summed over the sinks of a Fanout, and whether
+// any of them keeps stats; only the Kafka sink does.
+func StatsOf(sink EventSink) (Stats, bool) {
+	switch sink := sink.(type) {
+	case *KafkaSink:
+		return sink.Stats(), true
+	case Fanout:
+		var total Stats
+		var any bool
+		for _, s := range sink {
+			if stats, ok := StatsOf(s); ok {
+				total.Dropped += stats.Dropped
+				total.Failed += stats.Failed
+				any = true
+			}
+		}
+		return total, any
+	}
+	return Stats{}, false
+}
+
+// Fanout sends every event to each of its sinks.
+type Fanout []EventSink
+
+// Emit emits to every sink, even after one fails, and joins the errors.
+func (f Fanout) Emit(ctx context.Context, event Event) error {
+	var errs []error
+	for _, sink := range f {
+		errs = append(errs, sink.Emit(ctx, event))
+	}
+	return errors.Join(errs...)
+}
+
+// Publish publishes to every sink. If any fails the relay retries the batch, so sinks that
+// succeeded may see it again; delivery is at least once either way.
+func (f Fanout) Publish(ctx context.Context, encoded ...[]byte) error {
+	var errs []error
+	for _, sink := range f {
+		errs = append(errs, sink.Publish(ctx, encoded...))
+	}
+	return errors.Join(errs...)
+}
+
+func (f Fanout) Close() error {
+	var errs []error
+	for _, sink := range f {
+		errs = append(errs, sink.Close())
+	}
+	return errors.Join(errs...)
+}
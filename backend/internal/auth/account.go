@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// GuestPrefix starts every generated guest name; registered names may not use it.
+const GuestPrefix = "guest-"
+
+const (
+	guestAlphabet     = "abcdefghjkmnpqrstuvwxyz23456789"
+	guestSuffixLength = 6
+	minPasswordLength = 8
+	// maxPasswordLength is bcrypt's input limit; longer passwords would be silently truncated.
+	maxPasswordLength = 72
+)
+
+var (
+	ErrBadUsername      = errors.New("username must be 3-20 letters, digits, '_' or '-'")
+	ErrReservedUsername = errors.New("username is reserved")
+	ErrBadPassword      = errors.New("password must be 8-72 characters")
+	ErrWrongCredentials = errors.New("wrong username or password")
+	usernamePattern     = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+	reservedUsernames   = map[string]bool{"bot": true}
+)
+
+// ValidateUsername checks a name chosen at registration.
+func ValidateUsername(name string) error {
+	if !usernamePattern.MatchString(name) {
+		return ErrBadUsername
+	}
+	if reservedUsernames[strings.ToLower(name)] || strings.HasPrefix(strings.ToLower(name), GuestPrefix) {
+		return ErrReservedUsername
+	}
+	return nil
+}
+
+// HashPassword returns the bcrypt hash stored for a new account.
+func HashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", ErrBadPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// CheckPassword compares a login attempt against the stored hash.
+func CheckPassword(hash, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return ErrWrongCredentials
+	}
+	return nil
+}
+
+// GuestName generates a random name such as "guest-k7m2qa" for a player who did not register.
+func GuestName() (string, error) {
+	buf := make([]byte, guestSuffixLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = guestAlphabet[int(b)%len(guestAlphabet)]
+	}
+	return GuestPrefix + string(buf), nil
+}
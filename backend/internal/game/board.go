@@ -1,42 +1,187 @@
 package game
 
-import "errors"
-
-const (
-	Rows    = 6
-	Columns = 7
+import (
+	"encoding/json"
+	"errors"
+	"sync"
 )
 
 var (
-	errColumnFull = errors.New("column is full")
-	errBadColumn  = errors.New("invalid column")
+	errColumnFull    = errors.New("column is full")
+	errBadColumn     = errors.New("invalid column")
+	errPopNotAllowed = errors.New("popping discs is not allowed in this variant")
+	errCannotPop     = errors.New("bottom disc of this column is not yours")
 )
 
+// Board is a bitboard: bit col*colBits+h is set in discs[p-1] when player p owns the cell h
+// rows above the bottom of column col. Row indexes in the API count from the top, as in the
+// "cells" JSON array.
 type Board struct {
-	Cells [Rows][Columns]int `json:"cells"`
+	layout *layout
+	discs  [2]uint64
+	height [maxColumns]int
+}
+
+// layout holds the masks and shifts derived from a set of rules, shared by every board using them.
+type layout struct {
+	Rules
+	// colBits is the number of bits per column: one per row plus an always-empty sentinel on top
+	// so shifted lines never wrap into the next column.
+	colBits int
+	// shifts step to the next cell vertically, horizontally and along both diagonals.
+	shifts [4]uint
+	// columnMask has a bit for every playable cell of column 0; boardMask for every playable cell;
+	// centerMask for the cells of the center column.
+	columnMask uint64
+	boardMask  uint64
+	centerMask uint64
+	// order lists columns from the center outwards so the search sees the strongest moves first.
+	order []int
+}
+
+var layouts sync.Map // Rules -> *layout
+
+func layoutFor(r Rules) *layout {
+	if l, ok := layouts.Load(r); ok {
+		return l.(*layout)
+	}
+	colBits := r.Rows + 1
+	l := &layout{
+		Rules:   r,
+		colBits: colBits,
+		shifts:  [4]uint{1, uint(colBits), uint(colBits - 1), uint(colBits + 1)},
+	}
+	column := uint64(1)<<uint(r.Rows) - 1
+	l.columnMask = column
+	for c := 0; c < r.Columns; c++ {
+		l.boardMask |= column << uint(c*colBits)
+	}
+	center := r.Columns / 2
+	l.centerMask = column << uint(center*colBits)
+	l.order = append(l.order, center)
+	for offset := 1; offset <= center; offset++ {
+		if center-offset >= 0 {
+			l.order = append(l.order, center-offset)
+		}
+		if center+offset < r.Columns {
+			l.order = append(l.order, center+offset)
+		}
+	}
+	actual, _ := layouts.LoadOrStore(r, l)
+	return actual.(*layout)
+}
+
+// NewBoard returns an empty board for the rules, which must already be valid.
+func NewBoard(r Rules) Board {
+	return Board{layout: layoutFor(r)}
+}
+
+// Rules returns the rules the board was created with.
+func (b *Board) Rules() Rules {
+	return b.layout.Rules
 }
 
-func NewBoard() Board {
-	return Board{}
+func (b *Board) cellBit(row, col int) uint64 {
+	return 1 << uint(col*b.layout.colBits+b.layout.Rows-1-row)
 }
 
 // Drop places a disc for the player (1 or 2) in the given column. Returns the row index used.
 func (b *Board) Drop(col int, player int) (int, error) {
-	if col < 0 || col >= Columns {
+	if col < 0 || col >= b.layout.Columns {
 		return -1, errBadColumn
 	}
-	for row := Rows - 1; row >= 0; row-- {
-		if b.Cells[row][col] == 0 {
-			b.Cells[row][col] = player
-			return row, nil
+	if b.height[col] == b.layout.Rows {
+		return -1, errColumnFull
+	}
+	row := b.layout.Rows - 1 - b.height[col]
+	b.discs[player-1] |= b.cellBit(row, col)
+	b.height[col]++
+	return row, nil
+}
+
+// CanDrop reports whether col is on the board and has room for another disc.
+func (b *Board) CanDrop(col int) bool {
+	return col >= 0 && col < b.layout.Columns && b.height[col] < b.layout.Rows
+}
+
+// Pop removes the player's disc from the bottom of the column and lets the discs above it fall
+// one row. Only allowed when the rules enable PopOut.
+func (b *Board) Pop(col int, player int) error {
+	if !b.layout.PopOut {
+		return errPopNotAllowed
+	}
+	if col < 0 || col >= b.layout.Columns {
+		return errBadColumn
+	}
+	if !b.CanPop(col, player) {
+		return errCannotPop
+	}
+	column := b.layout.columnMask << uint(col*b.layout.colBits)
+	for i, discs := range b.discs {
+		// Shifting right drops the bottom cell; masking discards what spilled into the column below.
+		b.discs[i] = discs&^column | (discs&column)>>1&column
+	}
+	b.height[col]--
+	return nil
+}
+
+// play applies a drop or pop for the player; an empty kind means a drop.
+func (b *Board) play(m Move, player int) error {
+	switch m.Kind {
+	case MoveDrop, "":
+		_, err := b.Drop(m.Column, player)
+		return err
+	case MovePop:
+		return b.Pop(m.Column, player)
+	}
+	return ErrBadMoveKind
+}
+
+// CanPop reports whether the rules allow popping and the player owns the bottom disc of col.
+func (b *Board) CanPop(col int, player int) bool {
+	if !b.layout.PopOut || col < 0 || col >= b.layout.Columns || b.height[col] == 0 {
+		return false
+	}
+	return b.Cell(b.layout.Rows-1, col) == player
+}
+
+// HasMove reports whether the player has any legal drop or pop.
+func (b *Board) HasMove(player int) bool {
+	for c := 0; c < b.layout.Columns; c++ {
+		if b.CanDrop(c) || b.CanPop(c, player) {
+			return true
 		}
 	}
-	return -1, errColumnFull
+	return false
+}
+
+// Cell returns the player (1 or 2) occupying the cell, or 0 if it is empty.
+func (b *Board) Cell(row, col int) int {
+	bit := b.cellBit(row, col)
+	switch {
+	case b.discs[0]&bit != 0:
+		return 1
+	case b.discs[1]&bit != 0:
+		return 2
+	}
+	return 0
+}
+
+// Cells expands the bitboard into the row-major grid used on the wire.
+func (b *Board) Cells() [][]int {
+	cells := make([][]int, b.layout.Rows)
+	for r := range cells {
+		cells[r] = make([]int, b.layout.Columns)
+		for c := range cells[r] {
+			cells[r][c] = b.Cell(r, c)
+		}
+	}
+	return cells
 }
 
 func (b *Board) IsFull() bool {
-	for c := 0; c < Columns; c++ {
-		if b.Cells[0][c] == 0 {
+	for c := 0; c < b.layout.Columns; c++ {
+		if b.height[c] < b.layout.Rows {
 			return false
 		}
 	}
@@ -45,33 +190,68 @@ func (b *Board) IsFull() bool {
 
 // Winner returns the winning player number (1 or 2), or 0 if no winner.
 func (b *Board) Winner() int {
-	directions := [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
-	for r := 0; r < Rows; r++ {
-		for c := 0; c < Columns; c++ {
-			player := b.Cells[r][c]
-			if player == 0 {
-				continue
-			}
-			for _, d := range directions {
-				if b.streak(r, c, d[0], d[1], player) {
-					return player
-				}
-			}
+	for player := 1; player <= 2; player++ {
+		if b.hasWon(player) {
+			return player
 		}
 	}
 	return 0
 }
 
-func (b *Board) streak(r, c, dr, dc, player int) bool {
-	for i := 1; i < 4; i++ {
-		rr := r + dr*i
-		cc := c + dc*i
-		if rr < 0 || rr >= Rows || cc < 0 || cc >= Columns {
-			return false
+func (b *Board) hasWon(player int) bool {
+	mask := b.discs[player-1]
+	for _, shift := range b.layout.shifts {
+		line := mask
+		for i := 1; i < b.layout.Connect && line != 0; i++ {
+			line &= mask >> (uint(i) * shift)
 		}
-		if b.Cells[rr][cc] != player {
-			return false
+		if line != 0 {
+			return true
 		}
 	}
-	return true
+	return false
+}
+
+type boardJSON struct {
+	Cells   [][]int `json:"cells"`
+	Connect int     `json:"connect"`
+	PopOut  bool    `json:"popOut,omitempty"`
+}
+
+func (b Board) MarshalJSON() ([]byte, error) {
+	return json.Marshal(boardJSON{Cells: b.Cells(), Connect: b.layout.Connect, PopOut: b.layout.PopOut})
+}
+
+// UnmarshalJSON rebuilds the bitboard from the "cells" grid, taking the dimensions from its shape.
+func (b *Board) UnmarshalJSON(data []byte) error {
+	var in boardJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	rules := Rules{Rows: len(in.Cells), Connect: in.Connect, PopOut: in.PopOut}
+	if rules.Rows > 0 {
+		rules.Columns = len(in.Cells[0])
+	}
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+	*b = NewBoard(rules)
+	for c := 0; c < rules.Columns; c++ {
+		for r := rules.Rows - 1; r >= 0; r-- {
+			if len(in.Cells[r]) != rules.Columns {
+				return errors.New("ragged cells grid")
+			}
+			player := in.Cells[r][c]
+			switch {
+			case player == 0:
+				continue
+			case player != 1 && player != 2:
+				return errors.New("invalid cell value")
+			case b.height[c] != rules.Rows-1-r:
+				return errors.New("disc floating above an empty cell")
+			}
+			_, _ = b.Drop(c, player)
+		}
+	}
+	return nil
 }
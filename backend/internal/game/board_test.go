@@ -0,0 +1,235 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// stack drops players into col from the bottom up.
+func stack(t *testing.T, board *Board, col int, players ...int) {
+	t.Helper()
+	for _, p := range players {
+		if _, err := board.Drop(col, p); err != nil {
+			t.Fatalf("drop player %d in column %d: %v", p, col, err)
+		}
+	}
+}
+
+// repeat returns n copies of player, for filling a column below the discs under test.
+func repeat(player, n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = player
+	}
+	return out
+}
+
+func TestWinnerInEveryDirection(t *testing.T) {
+	cases := []struct {
+		name string
+		// build places player p's line, with other's discs underneath where needed.
+		build func(t *testing.T, b *Board, p, other int)
+	}{
+		{"horizontal", func(t *testing.T, b *Board, p, other int) {
+			for col := 2; col < 6; col++ {
+				stack(t, b, col, p)
+			}
+		}},
+		{"vertical", func(t *testing.T, b *Board, p, other int) {
+			stack(t, b, 4, p, p, p, p)
+		}},
+		{"rising diagonal", func(t *testing.T, b *Board, p, other int) {
+			for i := 0; i < 4; i++ {
+				stack(t, b, 1+i, append(repeat(other, i), p)...)
+			}
+		}},
+		{"falling diagonal", func(t *testing.T, b *Board, p, other int) {
+			for i := 0; i < 4; i++ {
+				stack(t, b, 5-i, append(repeat(other, i), p)...)
+			}
+		}},
+	}
+	for _, tc := range cases {
+		for p := 1; p <= 2; p++ {
+			board := NewBoard(ClassicRules)
+			tc.build(t, &board, p, 3-p)
+			if got := board.Winner(); got != p {
+				t.Errorf("%s line of player %d: winner %d", tc.name, p, got)
+			}
+		}
+	}
+}
+
+func TestThreeInARowIsNoWin(t *testing.T) {
+	board := NewBoard(ClassicRules)
+	stack(t, &board, 0, 1, 1, 1)
+	stack(t, &board, 1, 2, 2, 2)
+	stack(t, &board, 2, 2, 1)
+	stack(t, &board, 3, 2, 2, 1)
+	if got := board.Winner(); got != 0 {
+		t.Errorf("winner %d, want none", got)
+	}
+}
+
+// largestBoards fill all 64 bits, or as many as fit, in each direction.
+var largestBoards = []Rules{
+	{Rows: 15, Columns: 4, Connect: 4},
+	{Rows: 7, Columns: 8, Connect: 4},
+	{Rows: 4, Columns: 12, Connect: 4},
+	{Rows: 4, Columns: 12, Connect: 12},
+}
+
+func TestWinsAtTheEdgesOfTheLargestBoards(t *testing.T) {
+	const p, other = 1, 2
+	for _, rules := range largestBoards {
+		if err := rules.Validate(); err != nil {
+			t.Fatalf("%+v: %v", rules, err)
+		}
+		n, last := rules.Connect, rules.Columns-1
+		cases := []struct {
+			name  string
+			build func(b *Board)
+			won   bool
+		}{
+			{"vertical in the first column", func(b *Board) {
+				if n <= rules.Rows {
+					stack(t, b, 0, repeat(p, n)...)
+				}
+			}, n <= rules.Rows},
+			{"vertical to the top of the last column", func(b *Board) {
+				if n <= rules.Rows {
+					stack(t, b, last, append(repeat(other, rules.Rows-n), repeat(p, n)...)...)
+				}
+			}, n <= rules.Rows},
+			{"bottom row from the first column", func(b *Board) {
+				for col := 0; col < n; col++ {
+					stack(t, b, col, p)
+				}
+			}, n <= rules.Columns},
+			{"top row to the last column", func(b *Board) {
+				for col := rules.Columns - n; col <= last; col++ {
+					stack(t, b, col, append(repeat(other, rules.Rows-1), p)...)
+				}
+			}, n <= rules.Columns},
+			{"rising diagonal to the last column", func(b *Board) {
+				if n <= rules.Rows {
+					for i := 0; i < n; i++ {
+						stack(t, b, rules.Columns-n+i, append(repeat(other, i), p)...)
+					}
+				}
+			}, n <= rules.Rows},
+			{"falling diagonal from the top of the first column", func(b *Board) {
+				if n <= rules.Rows {
+					for i := 0; i < n; i++ {
+						stack(t, b, i, append(repeat(other, rules.Rows-1-i), p)...)
+					}
+				}
+			}, n <= rules.Rows},
+			// Without the sentinel bit the top of one column would touch the bottom of the next.
+			{"top of one column and bottom of the next", func(b *Board) {
+				if half := n / 2; n-half <= rules.Rows {
+					stack(t, b, 0, append(repeat(other, rules.Rows-half), repeat(p, half)...)...)
+					stack(t, b, 1, repeat(p, n-half)...)
+				}
+			}, false},
+			{"top of the last column", func(b *Board) {
+				stack(t, b, last, append(repeat(other, rules.Rows-1), p)...)
+			}, false},
+		}
+		for _, tc := range cases {
+			board := NewBoard(rules)
+			tc.build(&board)
+			if got := board.hasWon(p); got != tc.won {
+				t.Errorf("%dx%d connect %d, %s: won %v, want %v", rules.Columns, rules.Rows, n, tc.name, got, tc.won)
+			}
+		}
+	}
+}
+
+func TestPopCompletingBothLinesWinsForThePopper(t *testing.T) {
+	rules := Variants["popout"]
+	// The popper owns the bottom of column 3 and the row above the opponent's discs in columns
+	// 0-2; popping column 3 slides the opponent's disc into row 0 and the popper's into row 1.
+	cases := []struct {
+		name   string
+		drops  []int // alternating from player 1
+		popper int
+	}{
+		{"player 1 pops", []int{3, 3, 3, 0, 0, 1, 1, 2, 2, 6}, 1},
+		{"player 2 pops", []int{6, 3, 3, 3, 0, 0, 1, 1, 2, 2, 6}, 2},
+	}
+	for _, tc := range cases {
+		g := newGame(PlayerInfo{Username: "ann"}, PlayerInfo{Username: "ben"}, rules, TimeControl{})
+		names := [2]string{"ann", "ben"}
+		for i, col := range tc.drops {
+			if _, _, err := g.ApplyMove(names[i%2], MoveDrop, col); err != nil {
+				t.Fatalf("%s: drop %d in column %d: %v", tc.name, i+1, col, err)
+			}
+		}
+		if g.Done {
+			t.Fatalf("%s: game ended before the pop", tc.name)
+		}
+		_, winner, err := g.ApplyMove(names[tc.popper-1], MovePop, 3)
+		if err != nil {
+			t.Fatalf("%s: pop: %v", tc.name, err)
+		}
+		if !g.Board.hasWon(1) || !g.Board.hasWon(2) {
+			t.Fatalf("%s: the pop should complete a line for both players", tc.name)
+		}
+		if winner != tc.popper || g.Termination != TerminationConnect {
+			t.Errorf("%s: winner %d by %q, want %d by %q", tc.name, winner, g.Termination, tc.popper, TerminationConnect)
+		}
+	}
+}
+
+func TestBoardJSONRoundTrip(t *testing.T) {
+	boards := map[string]Board{"empty": NewBoard(ClassicRules)}
+	for name, rules := range Variants {
+		board := NewBoard(rules)
+		for i := 0; i < rules.Rows*rules.Columns/2; i++ {
+			col := (i * 3) % rules.Columns
+			if board.CanDrop(col) {
+				_, _ = board.Drop(col, i%2+1)
+			}
+		}
+		boards[name] = board
+	}
+	for _, rules := range largestBoards {
+		board := NewBoard(rules)
+		stack(t, &board, rules.Columns-1, repeat(2, rules.Rows)...)
+		boards[fmt.Sprintf("%dx%d connect %d", rules.Columns, rules.Rows, rules.Connect)] = board
+	}
+	for name, board := range boards {
+		data, err := json.Marshal(board)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", name, err)
+		}
+		var got Board
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: unmarshal %s: %v", name, data, err)
+		}
+		if got.Rules() != board.Rules() || got.discs != board.discs || got.height != board.height {
+			t.Errorf("%s: round trip changed the board: %s", name, data)
+		}
+		if !reflect.DeepEqual(got.Cells(), board.Cells()) {
+			t.Errorf("%s: cells differ after the round trip", name)
+		}
+	}
+}
+
+func TestBoardJSONRejectsBadGrids(t *testing.T) {
+	cases := map[string]string{
+		"floating disc": `{"cells":[[1,0,0,0],[0,0,0,0],[0,0,0,0],[0,0,0,0]],"connect":4}`,
+		"bad cell":      `{"cells":[[0,0,0,0],[0,0,0,0],[0,0,0,0],[0,0,3,0]],"connect":4}`,
+		"ragged":        `{"cells":[[0,0,0,0],[0,0,0],[0,0,0,0],[0,0,0,0]],"connect":4}`,
+		"too small":     `{"cells":[[0,0,0],[0,0,0],[0,0,0]],"connect":3}`,
+	}
+	for name, data := range cases {
+		var board Board
+		if err := json.Unmarshal([]byte(data), &board); err == nil {
+			t.Errorf("%s: accepted %s", name, data)
+		}
+	}
+}
@@ -1,65 +1,84 @@
 package game
 
-import "math/rand"
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
 
 type Bot struct {
-	Mark       int
-	Opponent   int
+	Mark     int
+	Opponent int
+	Depth    int
+	// Budget caps the wall-clock time of one search; zero searches the full Depth however long
+	// that takes.
+	Budget     time.Duration
+	Blunder    float64 // probability of a random legal move; needs RandomSeed
 	RandomSeed *rand.Rand
 }
 
 func NewBot(mark int, opponent int, rng *rand.Rand) *Bot {
-	return &Bot{Mark: mark, Opponent: opponent, RandomSeed: rng}
+	return &Bot{Mark: mark, Opponent: opponent, Depth: DefaultSearchDepth, RandomSeed: rng}
 }
 
-// ChooseMove picks a column using simple strategy: win if possible, block opponent, prefer center, otherwise first available.
-func (b *Bot) ChooseMove(board Board) int {
-	// 1) Can we win now?
-	if col, ok := b.findWinningMove(board, b.Mark); ok {
-		return col
+// ChooseMove searches with negamax and alpha-beta pruning, deepening one ply at a time up to
+// Depth until Budget runs out or ctx ends, and returns the best drop or pop of the deepest
+// search that finished. One ply is always searched in full. Ties are broken randomly when
+// RandomSeed is set, otherwise the most central column wins. The returned move has no By; ok
+// is false when the bot has no legal move.
+func (b *Bot) ChooseMove(ctx context.Context, board Board) (Move, bool) {
+	var buf [2 * maxColumns]Move
+	moves := legalMoves(&board, b.Mark, buf[:0])
+	if len(moves) == 0 {
+		return Move{}, false
 	}
-	// 2) Can we block opponent immediate win?
-	if col, ok := b.findWinningMove(board, b.Opponent); ok {
-		return col
+	if b.RandomSeed != nil && b.Blunder > 0 && b.RandomSeed.Float64() < b.Blunder {
+		return moves[b.RandomSeed.Intn(len(moves))], true
 	}
-	// 3) Prefer center column if available
-	center := Columns / 2
-	if b.canPlay(board, center) {
-		return center
-	}
-	// 4) Prefer columns towards center
-	order := []int{3, 2, 4, 1, 5, 0, 6}
-	for _, col := range order {
-		if b.canPlay(board, col) {
-			return col
-		}
+
+	if b.Budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.Budget)
+		defer cancel()
 	}
-	// 5) Fallback any available
-	for c := 0; c < Columns; c++ {
-		if b.canPlay(board, c) {
-			return c
+	// The first ply is never interrupted, so there is always a move to return.
+	best, bestScore := b.searchRoot(&searcher{ctx: context.Background()}, board, moves, 1)
+	for depth := 2; depth <= b.Depth && bestScore < winScore; depth++ {
+		s := &searcher{ctx: ctx}
+		found, score := b.searchRoot(s, board, moves, depth)
+		if s.stopped {
+			break
 		}
+		best, bestScore = found, score
 	}
-	return -1
-}
 
-func (b *Bot) canPlay(board Board, col int) bool {
-	if col < 0 || col >= Columns {
-		return false
+	if b.RandomSeed != nil {
+		return best[b.RandomSeed.Intn(len(best))], true
 	}
-	return board.Cells[0][col] == 0
+	return best[0], true
 }
 
-func (b *Bot) findWinningMove(board Board, mark int) (int, bool) {
-	for c := 0; c < Columns; c++ {
-		if !b.canPlay(board, c) {
-			continue
+// searchRoot scores every move depth plies deep and returns the best ones with their score.
+func (b *Bot) searchRoot(s *searcher, board Board, moves []Move, depth int) ([]Move, int) {
+	best := make([]Move, 0, len(moves))
+	bestScore := math.MinInt
+	for _, m := range moves {
+		child := board
+		_ = child.play(m, b.Mark)
+		// Search with a window one wider than the best score so equal moves are still scored exactly.
+		alpha := -math.MaxInt
+		if bestScore != math.MinInt {
+			alpha = bestScore - 1
 		}
-		temp := board
-		_, _ = temp.Drop(c, mark)
-		if temp.Winner() == mark {
-			return c, true
+		score := s.scoreMove(child, depth, alpha, math.MaxInt, b.Mark, b.Opponent)
+		switch {
+		case score > bestScore:
+			bestScore = score
+			best = append(best[:0], m)
+		case score == bestScore:
+			best = append(best, m)
 		}
 	}
-	return 0, false
+	return best, bestScore
 }
@@ -0,0 +1,90 @@
+package game
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+// boardAfter plays cols in turn, starting with player 1, and fails the test on an illegal drop.
+func boardAfter(t *testing.T, rules Rules, cols ...int) Board {
+	t.Helper()
+	board := NewBoard(rules)
+	for i, col := range cols {
+		if _, err := board.Drop(col, i%2+1); err != nil {
+			t.Fatalf("drop %d in column %d: %v", i+1, col, err)
+		}
+	}
+	return board
+}
+
+var difficulties = []Difficulty{DifficultyBeginner, DifficultyCasual, DifficultyExpert, DifficultyMax}
+
+func TestBotFindsForcedMove(t *testing.T) {
+	connect5 := Variants["connect5"]
+	cases := []struct {
+		name  string
+		rules Rules
+		cols  []int
+		want  int
+	}{
+		{"win horizontally", ClassicRules, []int{0, 0, 1, 1, 2, 6}, 3},
+		{"win vertically", ClassicRules, []int{0, 1, 0, 1, 0, 6}, 0},
+		{"win connect5", connect5, []int{0, 8, 1, 8, 2, 7, 3, 7}, 4},
+		{"block horizontally", ClassicRules, []int{0, 6, 1, 6, 2}, 3},
+		{"block vertically", ClassicRules, []int{0, 1, 0, 1, 0}, 0},
+		{"block connect5", connect5, []int{0, 8, 1, 8, 2, 7, 3}, 4},
+	}
+	for _, d := range difficulties {
+		for _, tc := range cases {
+			t.Run(string(d)+"/"+tc.name, func(t *testing.T) {
+				board := boardAfter(t, tc.rules, tc.cols...)
+				mark := len(tc.cols)%2 + 1
+				bot := NewBotForDifficulty(mark, 3-mark, d, rand.New(rand.NewSource(1)))
+				// Blunders are random by design; this checks the search.
+				bot.Blunder = 0
+				move, ok := bot.ChooseMove(context.Background(), board)
+				if !ok {
+					t.Fatal("no move")
+				}
+				if move.Kind != MoveDrop || move.Column != tc.want {
+					t.Errorf("got %s in column %d, want drop in column %d", move.Kind, move.Column, tc.want)
+				}
+			})
+		}
+	}
+}
+
+func TestBotIsDeterministicForSeed(t *testing.T) {
+	play := func() []Move {
+		board := NewBoard(ClassicRules)
+		bots := [2]*Bot{
+			NewBotForDifficulty(1, 2, DifficultyCasual, rand.New(rand.NewSource(7))),
+			NewBotForDifficulty(2, 1, DifficultyCasual, rand.New(rand.NewSource(8))),
+		}
+		var moves []Move
+		for ply := 0; ply < 12 && board.Winner() == 0; ply++ {
+			bot := bots[ply%2]
+			// Without a budget the result cannot depend on how fast the machine is.
+			bot.Budget = 0
+			move, ok := bot.ChooseMove(context.Background(), board)
+			if !ok {
+				break
+			}
+			if err := board.play(move, bot.Mark); err != nil {
+				t.Fatalf("ply %d: %v", ply+1, err)
+			}
+			moves = append(moves, move)
+		}
+		return moves
+	}
+	first, second := play(), play()
+	if len(first) != len(second) {
+		t.Fatalf("got %d moves, then %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("move %d: got %+v, then %+v", i+1, first[i], second[i])
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package game
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrBadTimeControl = errors.New("time control must look like <base seconds>+<increment seconds>")
+
+// TimeControl is a chess-style clock: each player starts with BaseSeconds and gains
+// IncrementSeconds after every move. The zero value means an untimed game.
+type TimeControl struct {
+	BaseSeconds      int `json:"baseSeconds"`
+	IncrementSeconds int `json:"incrementSeconds"`
+}
+
+// ParseTimeControl parses "300+5" (or "300" for no increment); an empty string is untimed.
+func ParseTimeControl(s string) (TimeControl, error) {
+	if s == "" {
+		return TimeControl{}, nil
+	}
+	base, inc, hasInc := strings.Cut(s, "+")
+	tc := TimeControl{}
+	var err error
+	if tc.BaseSeconds, err = strconv.Atoi(base); err != nil || tc.BaseSeconds <= 0 {
+		return TimeControl{}, ErrBadTimeControl
+	}
+	if hasInc {
+		if tc.IncrementSeconds, err = strconv.Atoi(inc); err != nil || tc.IncrementSeconds < 0 {
+			return TimeControl{}, ErrBadTimeControl
+		}
+	}
+	return tc, nil
+}
+
+func (tc TimeControl) Enabled() bool {
+	return tc.BaseSeconds > 0
+}
+
+func (tc TimeControl) String() string {
+	if !tc.Enabled() {
+		return ""
+	}
+	return strconv.Itoa(tc.BaseSeconds) + "+" + strconv.Itoa(tc.IncrementSeconds)
+}
+
+func (tc TimeControl) base() time.Duration {
+	return time.Duration(tc.BaseSeconds) * time.Second
+}
+
+func (tc TimeControl) increment() time.Duration {
+	return time.Duration(tc.IncrementSeconds) * time.Second
+}
+
+// TimeLeft returns how long the player to move has before their flag falls. ok is false for
+// untimed or finished games.
+func (g *Game) TimeLeft() (time.Duration, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if !g.TimeControl.Enabled() || g.Done {
+		return 0, false
+	}
+	return g.remainingLocked(g.Turn, time.Now()), true
+}
+
+// CheckFlag ends the game as a timeout loss if the player to move has run out of time.
+func (g *Game) CheckFlag() (*Game, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.TimeControl.Enabled() || g.Done {
+		return g.snapshotLocked(), false
+	}
+	now := time.Now()
+	if !g.outOfTimeLocked(now) {
+		return g.snapshotLocked(), false
+	}
+	g.flagLocked(now)
+	return g.snapshotLocked(), true
+}
+
+// remainingLocked is the player's clock at now, counting the running turn against the player to move.
+func (g *Game) remainingLocked(player int, now time.Time) time.Duration {
+	left := g.clocks[player-1]
+	if player == g.Turn && !g.Done {
+		left -= now.Sub(g.turnStarted)
+	}
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+func (g *Game) outOfTimeLocked(now time.Time) bool {
+	return g.TimeControl.Enabled() && g.remainingLocked(g.Turn, now) <= 0
+}
+
+// chargeMoveLocked stops the mover's clock once their move has been applied and adds the
+// increment. The turn start is tracked in untimed games too, for move think times.
+func (g *Game) chargeMoveLocked(now time.Time) {
+	if g.TimeControl.Enabled() {
+		g.clocks[g.Turn-1] = g.remainingLocked(g.Turn, now) + g.TimeControl.increment()
+	}
+	g.turnStarted = now
+}
+
+func (g *Game) flagLocked(now time.Time) {
+	g.clocks[g.Turn-1] = 0
+	g.end(otherPlayer(g.Turn), TerminationTimeout)
+	g.UpdatedAt = now
+	g.touchLocked()
+}
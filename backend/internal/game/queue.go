@@ -0,0 +1,83 @@
+package game
+
+import (
+	"math"
+	"time"
+)
+
+// queueScanInterval is how often a waiting player re-checks the queue as their window widens.
+const queueScanInterval = time.Second
+
+// RatingWindow controls how far apart in rating two queued players may be and still be paired.
+type RatingWindow struct {
+	Base      float64 // allowed difference as soon as a player joins
+	PerSecond float64 // growth of the allowed difference for every second waited
+}
+
+// allows reports whether a and b may be paired at now. The longer waiter's window applies so a
+// newcomer can be matched with someone who has already waited a while.
+func (w RatingWindow) allows(a, b *waitEntry, now time.Time) bool {
+	waited := max(now.Sub(a.joinedAt), now.Sub(b.joinedAt)).Seconds()
+	return math.Abs(a.rating-b.rating) <= w.Base+w.PerSecond*waited
+}
+
+// pairLocked looks for the queued player closest in rating to entry that the window allows. If
+// one is found, both leave the queue and the game is sent to each of their channels, the player
+// who queued first moving first. entry must be queued.
+func (m *Manager) pairLocked(entry *waitEntry, now time.Time) {
+	if m.queuedLocked(entry.username) != entry {
+		return
+	}
+	var best *waitEntry
+	for _, other := range m.queue {
+		if other == entry || other.key != entry.key || !m.window.allows(entry, other, now) {
+			continue
+		}
+		if best == nil || math.Abs(other.rating-entry.rating) < math.Abs(best.rating-entry.rating) {
+			best = other
+		}
+	}
+	if best == nil {
+		return
+	}
+	m.dequeueLocked(entry)
+	m.dequeueLocked(best)
+	first, second := best, entry
+	if entry.joinedAt.Before(best.joinedAt) {
+		first, second = entry, best
+	}
+	g := newGame(
+		PlayerInfo{Username: first.username, Rating: ratingValue(first.rating)},
+		PlayerInfo{Username: second.username, Rating: ratingValue(second.rating)},
+		entry.key.rules, entry.key.tc,
+	)
+	m.registerGame(g)
+	// Both channels are buffered and only ever receive this one result.
+	first.ch <- matchResult{game: g, playerIdx: playerOne}
+	second.ch <- matchResult{game: g, playerIdx: playerTwo}
+}
+
+func (m *Manager) queuedLocked(username string) *waitEntry {
+	for _, e := range m.queue {
+		if e.username == username {
+			return e
+		}
+	}
+	return nil
+}
+
+// dequeueLocked removes entry from the queue and reports whether it was still there.
+func (m *Manager) dequeueLocked(entry *waitEntry) bool {
+	for i, e := range m.queue {
+		if e == entry {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			m.lobbyChangedLocked()
+			return true
+		}
+	}
+	return false
+}
+
+func ratingValue(r float64) int {
+	return int(math.Round(r))
+}
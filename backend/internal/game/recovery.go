@@ -0,0 +1,43 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var errBadSnapshot = errors.New("game snapshot is inconsistent")
+
+// RestoreGame rebuilds an in-progress game from its JSON snapshot, e.g. after a restart. The
+// clock of the player to move restarts from the snapshot, so time the server was down is not
+// charged to anyone.
+func RestoreGame(data []byte) (*Game, error) {
+	g := &Game{}
+	if err := json.Unmarshal(data, g); err != nil {
+		return nil, err
+	}
+	if err := g.Rules.Validate(); err != nil {
+		return nil, err
+	}
+	if g.Board.layout == nil || g.Board.Rules() != g.Rules || (g.Turn != playerOne && g.Turn != playerTwo) {
+		return nil, errBadSnapshot
+	}
+	if g.TimeControl.Enabled() {
+		if g.RemainingMs == nil {
+			return nil, errBadSnapshot
+		}
+		for i, ms := range g.RemainingMs {
+			g.clocks[i] = time.Duration(ms) * time.Millisecond
+		}
+	}
+	g.RemainingMs = nil
+	g.turnStarted = time.Now()
+	return g, nil
+}
+
+// Restore makes a recovered game active again so its players rejoin it when they reconnect.
+func (m *Manager) Restore(g *Game) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.registerGame(g)
+}
@@ -0,0 +1,50 @@
+package game
+
+import "time"
+
+// rematchOffer is a pending request to replay a finished game.
+type rematchOffer struct {
+	from string
+	at   time.Time
+}
+
+// RequestRematch records that username wants to play the finished game prev again. Once both
+// players have asked within window, a new game between them with swapped colors and the same
+// rules and clock starts and is returned; until then it returns nil.
+func (m *Manager) RequestRematch(prev *Game, username string, window time.Duration) (*Game, error) {
+	state := prev.Snapshot()
+	if !state.Done {
+		return nil, ErrGameNotOver
+	}
+	if state.PlayerIndex(username) == 0 {
+		return nil, ErrNotYourGame
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	offer, ok := m.rematches[state.ID]
+	if !ok || offer.from == username || now.Sub(offer.at) > window {
+		m.rematches[state.ID] = rematchOffer{from: username, at: now}
+		time.AfterFunc(window, func() { m.expireRematch(state.ID, now) })
+		return nil, nil
+	}
+	delete(m.rematches, state.ID)
+
+	for _, p := range state.Players {
+		if id, busy := m.userGames[p.Username]; (busy && id != state.ID) || m.queuedLocked(p.Username) != nil {
+			return nil, ErrRematchUnavailable
+		}
+	}
+	g := newGame(state.Players[1], state.Players[0], state.Rules, state.TimeControl)
+	m.registerGame(g)
+	return g, nil
+}
+
+func (m *Manager) expireRematch(gameID string, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if offer, ok := m.rematches[gameID]; ok && offer.at.Equal(at) {
+		delete(m.rematches, gameID)
+	}
+}
@@ -0,0 +1,31 @@
+package game
+
+import "errors"
+
+var ErrBadMoveIndex = errors.New("move index is out of range")
+
+// Replay rebuilds the board of a recorded game after its first n moves. players holds the
+// usernames of player one and two and tells whose disc each move was.
+func Replay(rules Rules, players [2]string, moves []Move, n int) (Board, error) {
+	if err := rules.Validate(); err != nil {
+		return Board{}, err
+	}
+	if n < 0 || n > len(moves) {
+		return Board{}, ErrBadMoveIndex
+	}
+	board := NewBoard(rules)
+	for _, m := range moves[:n] {
+		player := playerOne
+		switch m.By {
+		case players[0]:
+		case players[1]:
+			player = playerTwo
+		default:
+			return Board{}, ErrNotYourGame
+		}
+		if err := board.play(m, player); err != nil {
+			return Board{}, err
+		}
+	}
+	return board, nil
+}
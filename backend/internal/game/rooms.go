@@ -0,0 +1,151 @@
+package game
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+)
+
+// roomCodeAlphabet leaves out look-alike characters (0/O, 1/I/L) so codes are easy to read out.
+const (
+	roomCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	roomCodeLength   = 6
+)
+
+// RoomInfo describes a private room as returned to the player who created it.
+type RoomInfo struct {
+	Code        string      `json:"code"`
+	CreatedBy   string      `json:"createdBy"`
+	Rules       Rules       `json:"rules"`
+	TimeControl TimeControl `json:"timeControl"`
+	ExpiresAt   time.Time   `json:"expiresAt"`
+}
+
+// room is a private waiting slot reachable only through its invite code. It never falls back to a bot.
+type room struct {
+	info    RoomInfo
+	waiting *waitEntry
+	expiry  *time.Timer
+}
+
+// CreateRoom reserves an invite code for a private game on behalf of creator. The room is
+// dropped if two players have not joined within ttl.
+func (m *Manager) CreateRoom(creator string, rules Rules, tc TimeControl, ttl time.Duration) (RoomInfo, error) {
+	if err := rules.Validate(); err != nil {
+		return RoomInfo{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code, err := m.newRoomCodeLocked()
+	if err != nil {
+		return RoomInfo{}, err
+	}
+	rm := &room{info: RoomInfo{Code: code, CreatedBy: creator, Rules: rules, TimeControl: tc, ExpiresAt: time.Now().Add(ttl)}}
+	rm.expiry = time.AfterFunc(ttl, func() { m.expireRoom(rm) })
+	m.rooms[code] = rm
+	return rm.info, nil
+}
+
+// RoomExists reports whether an invite code is open for joining.
+func (m *Manager) RoomExists(code string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.rooms[code]
+	return ok
+}
+
+// JoinRoom attaches a player to a private room. The first player blocks until a second one joins,
+// the room expires or ctx ends; the second starts the game. The first player opened the room,
+// so if they leave the room is closed and ctx's error returned. Returns the same values as
+// WaitForMatch.
+func (m *Manager) JoinRoom(ctx context.Context, code, username string) (*Game, int, bool, error) {
+	if g, idx, ok := m.findExisting(username); g != nil {
+		return g, idx, ok, nil
+	}
+
+	m.mu.Lock()
+	rm, ok := m.rooms[code]
+	if !ok {
+		m.mu.Unlock()
+		return nil, 0, false, ErrRoomNotFound
+	}
+	if rm.waiting == nil {
+		ch := make(chan matchResult, 1)
+		rm.waiting = &waitEntry{username: username, ch: ch}
+		m.mu.Unlock()
+
+		select {
+		case res, ok := <-ch:
+			if !ok {
+				return nil, 0, false, ErrRoomExpired
+			}
+			return res.game, res.playerIdx, false, nil
+		case <-ctx.Done():
+			if !m.closeRoom(rm) {
+				// Joined or expired just as the player left.
+				res, ok := <-ch
+				if !ok {
+					return nil, 0, false, ErrRoomExpired
+				}
+				return res.game, res.playerIdx, false, nil
+			}
+			return nil, 0, false, ctx.Err()
+		}
+	}
+
+	waiting := rm.waiting
+	if waiting.username == username {
+		m.mu.Unlock()
+		return nil, 0, false, ErrAlreadyWaiting
+	}
+	rm.expiry.Stop()
+	delete(m.rooms, code)
+	g := newGame(PlayerInfo{Username: waiting.username}, PlayerInfo{Username: username}, rm.info.Rules, rm.info.TimeControl)
+	m.registerGame(g)
+	m.mu.Unlock()
+
+	waiting.ch <- matchResult{game: g, playerIdx: playerOne}
+	return g, playerTwo, false, nil
+}
+
+// closeRoom drops a room whose first player left before anyone joined. It reports false if the
+// room was already completed or expired.
+func (m *Manager) closeRoom(rm *room) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rooms[rm.info.Code] != rm {
+		return false
+	}
+	rm.expiry.Stop()
+	delete(m.rooms, rm.info.Code)
+	return true
+}
+
+// expireRoom drops a room nobody completed in time and releases a player still waiting in it.
+func (m *Manager) expireRoom(rm *room) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rooms[rm.info.Code] != rm {
+		return
+	}
+	delete(m.rooms, rm.info.Code)
+	if rm.waiting != nil {
+		close(rm.waiting.ch)
+	}
+}
+
+func (m *Manager) newRoomCodeLocked() (string, error) {
+	buf := make([]byte, roomCodeLength)
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for i, b := range buf {
+			buf[i] = roomCodeAlphabet[int(b)%len(roomCodeAlphabet)]
+		}
+		code := string(buf)
+		if _, taken := m.rooms[code]; !taken {
+			return code, nil
+		}
+	}
+}
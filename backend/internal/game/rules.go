@@ -0,0 +1,70 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	minRows    = 4
+	minColumns = 4
+	minConnect = 3
+	// maxColumns bounds the per-column height array; with minRows every column needs at least
+	// five bits of the 64-bit board.
+	maxColumns = 64 / (minRows + 1)
+	// maxConnect is the longest line evaluate can count in its four-bit per-window counters.
+	// Connect must also fit the longer side of the board, and with minColumns columns of Rows+1
+	// bits a board has at most 15 rows, so no valid rules reach it today; the check keeps it so.
+	maxConnect = 15
+)
+
+var ErrBadVariant = errors.New("unknown variant")
+
+// Rules describes the board a game is played on and how many discs in a row win.
+type Rules struct {
+	Rows    int `json:"rows"`
+	Columns int `json:"columns"`
+	Connect int `json:"connect"`
+	// PopOut lets players remove one of their own discs from the bottom row instead of dropping.
+	PopOut bool `json:"popOut,omitempty"`
+}
+
+// ClassicRules is the standard 7x6 Connect Four board.
+var ClassicRules = Rules{Rows: 6, Columns: 7, Connect: 4}
+
+// Variants are the rule sets offered at matchmaking, keyed by the name clients send.
+var Variants = map[string]Rules{
+	"classic":  ClassicRules,
+	"8x7":      {Rows: 7, Columns: 8, Connect: 4},
+	"connect5": {Rows: 6, Columns: 9, Connect: 5},
+	"popout":   {Rows: 6, Columns: 7, Connect: 4, PopOut: true},
+}
+
+// ParseVariant resolves a variant name; an empty string selects the classic rules.
+func ParseVariant(name string) (Rules, error) {
+	if name == "" {
+		return ClassicRules, nil
+	}
+	rules, ok := Variants[name]
+	if !ok {
+		return Rules{}, ErrBadVariant
+	}
+	return rules, nil
+}
+
+// Validate checks the rules fit in a bitboard and can actually be won.
+func (r Rules) Validate() error {
+	switch {
+	case r.Rows < minRows || r.Columns < minColumns:
+		return fmt.Errorf("board must be at least %dx%d", minColumns, minRows)
+	case (r.Rows+1)*r.Columns > 64:
+		return fmt.Errorf("board %dx%d is too large", r.Columns, r.Rows)
+	case r.Connect < minConnect:
+		return fmt.Errorf("connect must be at least %d", minConnect)
+	case r.Connect > maxConnect:
+		return fmt.Errorf("connect must be at most %d", maxConnect)
+	case r.Connect > r.Rows && r.Connect > r.Columns:
+		return fmt.Errorf("connect %d does not fit on a %dx%d board", r.Connect, r.Columns, r.Rows)
+	}
+	return nil
+}
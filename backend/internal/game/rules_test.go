@@ -0,0 +1,46 @@
+package game
+
+import "testing"
+
+func TestRulesValidate(t *testing.T) {
+	cases := []struct {
+		rules Rules
+		ok    bool
+	}{
+		{ClassicRules, true},
+		{Rules{Rows: 4, Columns: 4, Connect: 3}, true},
+		{Rules{Rows: 15, Columns: 4, Connect: 15}, true},
+		{Rules{Rows: 4, Columns: 12, Connect: 12}, true},
+		{Rules{Rows: 7, Columns: 8, Connect: 8}, true},
+		{Rules{Rows: 3, Columns: 7, Connect: 3}, false},
+		{Rules{Rows: 6, Columns: 3, Connect: 3}, false},
+		{Rules{Rows: 16, Columns: 4, Connect: 4}, false},
+		{Rules{Rows: 4, Columns: 13, Connect: 4}, false},
+		{Rules{Rows: 8, Columns: 8, Connect: 4}, false},
+		{Rules{Rows: 6, Columns: 7, Connect: 2}, false},
+		{Rules{Rows: 6, Columns: 7, Connect: 8}, false},
+	}
+	for _, tc := range cases {
+		if err := tc.rules.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%+v: got %v, want ok %v", tc.rules, err, tc.ok)
+		}
+	}
+}
+
+func TestParseVariant(t *testing.T) {
+	for name, want := range Variants {
+		got, err := ParseVariant(name)
+		if err != nil || got != want {
+			t.Errorf("%q: got %+v, %v", name, got, err)
+		}
+		if err := got.Validate(); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	if got, err := ParseVariant(""); err != nil || got != ClassicRules {
+		t.Errorf("empty name: got %+v, %v", got, err)
+	}
+	if _, err := ParseVariant("hexagonal"); err != ErrBadVariant {
+		t.Errorf("unknown name: got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package game
+
+import (
+	"context"
+	"math/bits"
+)
+
+// DefaultSearchDepth is the number of plies the bot looks ahead when no depth is configured.
+const DefaultSearchDepth = 7
+
+// maxSearchDepth is deep enough to fill any board, the limit for searches bounded only by time.
+const maxSearchDepth = 64
+
+const (
+	// winScore is returned for a won position; remaining depth is added so faster wins score higher.
+	winScore = 1_000_000
+
+	threeWeight  = 5
+	twoWeight    = 2
+	centerWeight = 3
+)
+
+// searchCheckInterval is how many nodes the search visits between looks at the clock.
+const searchCheckInterval = 1024
+
+// searcher runs one negamax search and stops it when its context ends.
+type searcher struct {
+	ctx     context.Context
+	nodes   int
+	stopped bool
+}
+
+// expired reports whether the search must stop. Once it does, every score the search returns
+// is meaningless and the caller discards the whole iteration.
+func (s *searcher) expired() bool {
+	if s.stopped {
+		return true
+	}
+	s.nodes++
+	if s.nodes%searchCheckInterval == 0 && s.ctx.Err() != nil {
+		s.stopped = true
+	}
+	return s.stopped
+}
+
+// negamax returns the score of board for mark, who is about to move, searching depth plies with alpha-beta pruning.
+func (s *searcher) negamax(board Board, depth, alpha, beta, mark, opponent int) int {
+	if depth == 0 || s.expired() {
+		return evaluate(board, mark) - evaluate(board, opponent)
+	}
+	var buf [2 * maxColumns]Move
+	for _, m := range legalMoves(&board, mark, buf[:0]) {
+		child := board
+		_ = child.play(m, mark)
+		score := s.scoreMove(child, depth, alpha, beta, mark, opponent)
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return alpha
+}
+
+// scoreMove scores child, the board right after mark moved, from mark's point of view. Game-ending
+// positions are scored directly; when a pop connects both players the mover wins.
+func (s *searcher) scoreMove(child Board, depth, alpha, beta, mark, opponent int) int {
+	switch {
+	case child.hasWon(mark):
+		return winScore + depth
+	case child.hasWon(opponent):
+		return -(winScore + depth)
+	case !child.HasMove(opponent):
+		return 0
+	}
+	return -s.negamax(child, depth-1, -beta, -alpha, opponent, mark)
+}
+
+// legalMoves appends mark's moves to buf in search order: drops from the center outwards, then pops.
+func legalMoves(board *Board, mark int, buf []Move) []Move {
+	for _, col := range board.layout.order {
+		if board.CanDrop(col) {
+			buf = append(buf, Move{Column: col, Kind: MoveDrop})
+		}
+	}
+	if board.layout.PopOut {
+		for _, col := range board.layout.order {
+			if board.CanPop(col, mark) {
+				buf = append(buf, Move{Column: col, Kind: MovePop})
+			}
+		}
+	}
+	return buf
+}
+
+// evaluate scores the open lines mark owns: every window of Connect cells with no opposing disc
+// counts towards threes (one disc short of a win) and twos (two short), and discs in the center
+// column get a small bonus.
+func evaluate(board Board, mark int) int {
+	l := board.layout
+	own := board.discs[mark-1]
+	open := l.boardMask &^ board.discs[2-mark]
+	score := centerWeight * bits.OnesCount64(own&l.centerMask)
+	for _, shift := range l.shifts {
+		// windows has a bit at the lowest cell of every on-board window free of opposing discs.
+		windows := open
+		for i := 1; i < l.Connect; i++ {
+			windows &= open >> (uint(i) * shift)
+		}
+		if windows == 0 {
+			continue
+		}
+		// Count own discs per window with a bit-sliced counter: bit j of counts[k] is bit k of
+		// the count for the window starting at j. Four bits suffice because Validate caps
+		// Connect at maxConnect = 15.
+		var counts [4]uint64
+		for i := 0; i < l.Connect; i++ {
+			carry := (own >> (uint(i) * shift)) & windows
+			for k := range counts {
+				counts[k], carry = counts[k]^carry, counts[k]&carry
+			}
+		}
+		score += threeWeight * bits.OnesCount64(windowsWithCount(windows, counts, l.Connect-1))
+		score += twoWeight * bits.OnesCount64(windowsWithCount(windows, counts, l.Connect-2))
+	}
+	return score
+}
+
+func windowsWithCount(windows uint64, counts [4]uint64, n int) uint64 {
+	for k, slice := range counts {
+		if n>>k&1 == 1 {
+			windows &= slice
+		} else {
+			windows &^= slice
+		}
+	}
+	return windows
+}
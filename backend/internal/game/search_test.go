@@ -0,0 +1,31 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEvaluateIsSymmetricBetweenPlayers(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for _, name := range []string{"classic", "8x7", "connect5", "popout"} {
+		rules := Variants[name]
+		for game := 0; game < 20; game++ {
+			board := NewBoard(rules)
+			for ply := 0; board.Winner() == 0; ply++ {
+				mark := ply%2 + 1
+				swapped := board
+				swapped.discs[0], swapped.discs[1] = board.discs[1], board.discs[0]
+				for player := 1; player <= 2; player++ {
+					if got, want := evaluate(swapped, 3-player), evaluate(board, player); got != want {
+						t.Fatalf("%s game %d ply %d: player %d scores %d, %d with the colors swapped", name, game, ply, player, want, got)
+					}
+				}
+				moves := legalMoves(&board, mark, nil)
+				if len(moves) == 0 {
+					break
+				}
+				_ = board.play(moves[rng.Intn(len(moves))], mark)
+			}
+		}
+	}
+}
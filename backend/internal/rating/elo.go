@@ -0,0 +1,22 @@
+package rating
+
+import "math"
+
+const (
+	// Default is the rating a player starts with before their first rated game.
+	Default = 1500.0
+	// kFactor caps how many points a single game can move a rating.
+	kFactor = 32.0
+)
+
+// Expected returns the probability that a player rated a beats one rated b.
+func Expected(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// Update returns the new Elo ratings of a and b after a game where a scored scoreA
+// (1 for a win, 0.5 for a draw, 0 for a loss).
+func Update(a, b, scoreA float64) (float64, float64) {
+	delta := kFactor * (scoreA - Expected(a, b))
+	return a + delta, b - delta
+}
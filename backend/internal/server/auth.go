@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/auth"
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/storage"
+)
+
+// guestNameAttempts bounds retries when a generated guest name is already taken.
+const guestNameAttempts = 5
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// session is returned by every auth endpoint; clients pass the token to /ws.
+type session struct {
+	Token     string    `json:"token"`
+	Username  string    `json:"username"`
+	Guest     bool      `json:"guest,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var creds credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := auth.ValidateUsername(creds.Username); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hash, err := auth.HashPassword(creds.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.repo.CreatePlayer(r.Context(), storage.Player{Username: creds.Username, PasswordHash: hash, CreatedAt: time.Now()})
+	if errors.Is(err, storage.ErrUsernameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("register: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeSession(w, http.StatusCreated, creds.Username, false)
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var creds credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	player, err := s.repo.Player(r.Context(), creds.Username)
+	if err != nil && !errors.Is(err, storage.ErrPlayerNotFound) {
+		log.Printf("login: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err != nil || player.Guest || auth.CheckPassword(player.PasswordHash, creds.Password) != nil {
+		http.Error(w, auth.ErrWrongCredentials.Error(), http.StatusUnauthorized)
+		return
+	}
+	s.writeSession(w, http.StatusOK, player.Username, false)
+}
+
+// handleGuest creates an account with a generated name for a player who does not register.
+func (s *Server) handleGuest(w http.ResponseWriter, r *http.Request) {
+	for i := 0; i < guestNameAttempts; i++ {
+		name, err := auth.GuestName()
+		if err != nil {
+			log.Printf("guest name: %v", err)
+			break
+		}
+		err = s.repo.CreatePlayer(r.Context(), storage.Player{Username: name, Guest: true, CreatedAt: time.Now()})
+		if errors.Is(err, storage.ErrUsernameTaken) {
+			continue
+		}
+		if err != nil {
+			log.Printf("guest: %v", err)
+			break
+		}
+		s.writeSession(w, http.StatusCreated, name, true)
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+func (s *Server) writeSession(w http.ResponseWriter, status int, username string, guest bool) {
+	token, claims, err := s.signer.Issue(username, guest)
+	if err != nil {
+		log.Printf("issue token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(session{Token: token, Username: claims.Username, Guest: claims.Guest, ExpiresAt: claims.ExpiresAt})
+}
+
+// authenticate returns the player behind the request's session token, taken from an
+// "Authorization: Bearer" header or, for browser WebSockets that cannot set headers, ?token=.
+func (s *Server) authenticate(r *http.Request) (auth.Claims, error) {
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); header != "" {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+	if token == "" {
+		return auth.Claims{}, auth.ErrInvalidToken
+	}
+	return s.signer.Verify(token)
+}
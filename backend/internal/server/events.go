@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/analytics"
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/game"
+)
+
+// clientError sends err to the client and records it. action is what the client tried, usually
+// the type of the message that failed.
+func (s *Server) clientError(c *wsClient, g *game.Game, action string, err error) {
+	_ = c.send(game.ServerMessage{Type: "error", Error: err.Error()})
+	s.reportError(g, c.username, action, err)
+}
+
+// reportError records a failed player request; g is nil when the player has no game.
+func (s *Server) reportError(g *game.Game, username, action string, err error) {
+	gameID := ""
+	if g != nil {
+		gameID = g.ID
+	}
+	s.produceEvent(context.Background(), gameID, analytics.ClientError{Player: username, Action: action, Message: err.Error()})
+}
+
+// matchMade records a queued player leaving the queue for g after waiting waited.
+func (s *Server) matchMade(g *game.Game, username string, waited time.Duration) {
+	_, botFallback := g.Bot()
+	reason := "matched"
+	if botFallback {
+		reason = "bot"
+	}
+	ctx := context.Background()
+	s.produceEvent(ctx, g.ID, analytics.QueueLeft{Player: username, WaitMs: waited.Milliseconds(), Reason: reason})
+	s.produceEvent(ctx, g.ID, analytics.MatchMade{
+		Player:      username,
+		Opponent:    opponentName(g, username),
+		WaitMs:      waited.Milliseconds(),
+		BotFallback: botFallback,
+	})
+}
+
+// queueAbandoned records a player who disconnected after waiting waited, before being matched.
+func (s *Server) queueAbandoned(username string, waited time.Duration) {
+	s.produceEvent(context.Background(), "", analytics.QueueLeft{Player: username, WaitMs: waited.Milliseconds(), Reason: "abandoned"})
+}
+
+// movePlayed records the latest move of state.
+func (s *Server) movePlayed(state *game.Game) {
+	if len(state.Moves) == 0 {
+		return
+	}
+	move := state.Moves[len(state.Moves)-1]
+	s.produceEvent(context.Background(), state.ID, analytics.MovePlayed{
+		Player:  move.By,
+		Number:  len(state.Moves),
+		Column:  move.Column,
+		Kind:    moveKind(move.Kind),
+		ThinkMs: move.ThinkMs,
+	})
+}
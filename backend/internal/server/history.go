@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/game"
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/storage"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type gamePage struct {
+	Games  []storage.FinishedGame `json:"games"`
+	Total  int                    `json:"total"`
+	Limit  int                    `json:"limit"`
+	Offset int                    `json:"offset"`
+}
+
+// replayFrame is the position of a finished game after Move of its Moves moves.
+type replayFrame struct {
+	GameID   string     `json:"gameId"`
+	Move     int        `json:"move"`
+	Moves    int        `json:"moves"`
+	Board    game.Board `json:"board"`
+	LastMove *game.Move `json:"lastMove,omitempty"`
+}
+
+// handlePlayerGames lists a player's finished games, newest first, filtered by ?opponent=,
+// ?result=win|loss|draw and a ?from=/?to= date range, paged with ?limit= and ?offset=.
+func (s *Server) handlePlayerGames(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := storage.GameFilter{Username: r.PathValue("username"), Opponent: q.Get("opponent"), Result: q.Get("result")}
+	switch filter.Result {
+	case "", storage.ResultWin, storage.ResultLoss, storage.ResultDraw:
+	default:
+		http.Error(w, "result must be win, loss or draw", http.StatusBadRequest)
+		return
+	}
+	var err error
+	if filter.Limit, filter.Offset, err = parsePage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.From, err = parseDate(q.Get("from"), false); err != nil {
+		http.Error(w, "from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = parseDate(q.Get("to"), true); err != nil {
+		http.Error(w, "to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	games, total, err := s.repo.PlayerGames(r.Context(), filter)
+	if err != nil {
+		log.Printf("player games: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, gamePage{Games: games, Total: total, Limit: filter.Limit, Offset: filter.Offset})
+}
+
+// handleFinishedGame returns a stored game with its full move list.
+func (s *Server) handleFinishedGame(w http.ResponseWriter, r *http.Request) {
+	g, ok := s.loadFinishedGame(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+// handleReplay returns the board of a stored game after ?move= moves (default: the final position).
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	g, ok := s.loadFinishedGame(w, r)
+	if !ok {
+		return
+	}
+	var rules game.Rules
+	var moves []game.Move
+	if err := json.Unmarshal(g.Rules, &rules); err != nil {
+		log.Printf("replay %s: rules: %v", g.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := json.Unmarshal(g.Moves, &moves); err != nil {
+		log.Printf("replay %s: moves: %v", g.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	n := len(moves)
+	if v := r.URL.Query().Get("move"); v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "move must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+	board, err := game.Replay(rules, [2]string{g.Player1, g.Player2}, moves, n)
+	if errors.Is(err, game.ErrBadMoveIndex) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("replay %s: %v", g.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	frame := replayFrame{GameID: g.ID, Move: n, Moves: len(moves), Board: board}
+	if n > 0 {
+		frame.LastMove = &moves[n-1]
+	}
+	writeJSON(w, http.StatusOK, frame)
+}
+
+func (s *Server) loadFinishedGame(w http.ResponseWriter, r *http.Request) (storage.FinishedGame, bool) {
+	g, err := s.repo.FinishedGame(r.Context(), r.PathValue("id"))
+	if errors.Is(err, storage.ErrGameNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return g, false
+	}
+	if err != nil {
+		log.Printf("finished game: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return g, false
+	}
+	return g, true
+}
+
+// parsePage reads ?limit= (default 20, at most 100) and ?offset=.
+func parsePage(r *http.Request) (int, int, error) {
+	limit, offset := defaultPageSize, 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, errors.New("limit must be a positive number")
+		}
+		limit = min(n, maxPageSize)
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("offset must not be negative")
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+// parseDate accepts RFC 3339 timestamps or plain dates. A plain date used as the end of a range
+// includes the whole day.
+func parseDate(v string, end bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, errors.New("use YYYY-MM-DD or an RFC 3339 timestamp")
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
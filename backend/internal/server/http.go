@@ -3,42 +3,68 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"math/rand"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 
 	"github.com/rishirajmaheshwari/4-in-a-row/internal/analytics"
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/auth"
 	"github.com/rishirajmaheshwari/4-in-a-row/internal/config"
 	"github.com/rishirajmaheshwari/4-in-a-row/internal/game"
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/rating"
 	"github.com/rishirajmaheshwari/4-in-a-row/internal/storage"
 )
 
+var errNoGameYet = errors.New("still waiting for a game")
+
+// clockPollInterval bounds how late a flag fall is noticed after the other player moved.
+const clockPollInterval = 250 * time.Millisecond
+
 type Server struct {
-	cfg       config.Config
-	manager   *game.Manager
-	repo      *storage.Repository
-	producer  *analytics.Producer
-	upgrader  websocket.Upgrader
-	clients   map[string]map[string]*wsClient // gameID -> username -> client
-	mu        sync.Mutex
+	cfg        config.Config
+	manager    *game.Manager
+	repo       storage.Store
+	sink       analytics.EventSink // nil when analytics are disabled
+	relay      *analytics.Relay    // nil when analytics are disabled
+	signer     *auth.Signer
+	upgrader   websocket.Upgrader
+	clients    map[string]map[string]*wsClient     // gameID -> username -> client
+	spectators map[string]map[*wsClient]struct{}   // gameID -> read-only watchers
+	clocks     map[string]bool                     // gameID -> clock watcher running
+	rest       map[string]map[string]*restPresence // gameID -> username -> REST activity
+	mu         sync.Mutex
 }
 
 type wsClient struct {
 	username string
 	conn     *websocket.Conn
-	game     *game.Game
+	game     *game.Game // guarded by Server.mu once registered; a rematch moves the client on
 	player   int
+	closed   bool // the connection failed; guarded by Server.mu
+	writeMu  sync.Mutex
 }
 
-func New(cfg config.Config, manager *game.Manager, repo *storage.Repository, producer *analytics.Producer) *Server {
+// send serializes writes: the read loop, the opponent's read loop and clock watchers all write.
+func (c *wsClient) send(msg game.ServerMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
+func New(cfg config.Config, manager *game.Manager, repo storage.Store, sink analytics.EventSink, relay *analytics.Relay, signer *auth.Signer) *Server {
 	return &Server{
-		cfg:      cfg,
-		manager:  manager,
-		repo:     repo,
-		producer: producer,
+		cfg:     cfg,
+		manager: manager,
+		repo:    repo,
+		sink:    sink,
+		relay:   relay,
+		signer:  signer,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				origin := r.Header.Get("Origin")
@@ -50,7 +76,10 @@ func New(cfg config.Config, manager *game.Manager, repo *storage.Repository, pro
 				return origin == ""
 			},
 		},
-		clients: make(map[string]map[string]*wsClient),
+		clients:    make(map[string]map[string]*wsClient),
+		spectators: make(map[string]map[*wsClient]struct{}),
+		clocks:     make(map[string]bool),
+		rest:       make(map[string]map[string]*restPresence),
 	}
 }
 
@@ -60,27 +89,38 @@ func (s *Server) Routes() http.Handler {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
+	mux.HandleFunc("GET /metrics/analytics", s.handleAnalyticsStats)
 	mux.HandleFunc("/leaderboard", s.handleLeaderboard)
+	mux.HandleFunc("POST /auth/register", s.handleRegister)
+	mux.HandleFunc("POST /auth/login", s.handleLogin)
+	mux.HandleFunc("POST /auth/guest", s.handleGuest)
+	mux.HandleFunc("POST /rooms", s.handleCreateRoom)
+	mux.HandleFunc("POST /games", s.handleCreateGame)
+	mux.HandleFunc("GET /games/{id}", s.handleGetGame)
+	mux.HandleFunc("POST /games/{id}/moves", s.handleSubmitMove)
+	mux.HandleFunc("GET /games/{id}/wait", s.handleWaitGame)
+	mux.HandleFunc("GET /players/{username}/profile", s.handleProfile)
+	mux.HandleFunc("GET /players/{username}/games", s.handlePlayerGames)
+	mux.HandleFunc("GET /history/games/{id}", s.handleFinishedGame)
+	mux.HandleFunc("GET /history/games/{id}/board", s.handleReplay)
 	mux.HandleFunc("/ws", s.handleWS)
+	mux.HandleFunc("/ws/spectate", s.handleSpectate)
+	mux.HandleFunc("/lobby", s.handleLobby)
+	mux.HandleFunc("/ws/lobby", s.handleLobbyWS)
 	return mux
 }
 
-func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	rows, err := s.repo.Leaderboard(ctx, 20)
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.authenticate(r)
 	if err != nil {
-		log.Printf("leaderboard error: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(rows)
-}
+	username := claims.Username
 
-func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
-	username := r.URL.Query().Get("username")
-	if username == "" {
-		http.Error(w, "username required", http.StatusBadRequest)
+	join, status, err := s.joiner(r, username)
+	if err != nil {
+		http.Error(w, err.Error(), status)
 		return
 	}
 
@@ -90,105 +130,411 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	botInfo := game.PlayerInfo{Username: "bot", IsBot: true}
-	g, playerIdx, existing := s.manager.WaitForMatch(username, time.Duration(s.cfg.BotWaitSeconds)*time.Second, botInfo)
-	client := &wsClient{username: username, conn: conn, game: g, player: playerIdx}
-	s.registerClient(client)
+	// Reading starts before the player has a game, so closing the socket while queued is
+	// noticed and ends the wait.
+	client := &wsClient{username: username, conn: conn}
+	ctx, leave := context.WithCancel(context.Background())
+	defer leave()
+	go s.readLoop(client, leave)
+
+	g, playerIdx, existing, err := join(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			_ = client.send(game.ServerMessage{Type: "error", Error: err.Error()})
+			s.reportError(nil, username, "join", err)
+		}
+		conn.Close()
+		return
+	}
+	if existing {
+		s.produceEvent(context.Background(), g.ID, analytics.Reconnected{Player: username})
+	}
+	if !s.registerClient(client, g, playerIdx) {
+		// The socket closed just as the game was found; treat it as a disconnect.
+		go s.maybeForfeit(g, username)
+		return
+	}
 
 	// Send initial state to the joining client (with reconnect flag), then broadcast to all with correct turn flags
-	_ = conn.WriteJSON(game.ServerMessage{Type: "state", GameID: g.ID, State: g, YourTurn: g.Turn == playerIdx, Opponent: opponentName(g, username), Reconnect: existing})
-	s.broadcastState(g, "")
+	state := g.Snapshot()
+	_ = client.send(game.ServerMessage{Type: "state", GameID: g.ID, State: state, YourTurn: state.Turn == playerIdx, Opponent: opponentName(state, username), Reconnect: existing})
+	s.gameJoined(g, username)
+}
+
+// joiner validates the game settings of a /ws or POST /games request and returns the call that
+// puts the player into a game, or an HTTP status and error for a bad request.
+func (s *Server) joiner(r *http.Request, username string) (func(context.Context) (*game.Game, int, bool, error), int, error) {
+	// Private rooms carry their own rules and never fall back to a bot.
+	if code := strings.ToUpper(r.URL.Query().Get("room")); code != "" {
+		if !s.manager.RoomExists(code) {
+			if g, _, _ := s.manager.FindGame(username); g == nil {
+				return nil, http.StatusNotFound, game.ErrRoomNotFound
+			}
+		}
+		return func(ctx context.Context) (*game.Game, int, bool, error) {
+			return s.manager.JoinRoom(ctx, code, username)
+		}, 0, nil
+	}
+	difficulty, err := game.ParseDifficulty(r.URL.Query().Get("difficulty"))
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	rules, tc, err := s.parseGameSettings(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	playerRating, err := s.repo.Rating(r.Context(), username)
+	if err != nil {
+		log.Printf("rating lookup: %v", err)
+		playerRating = rating.Default
+	}
+	var queuedAt time.Time
+	req := game.MatchRequest{
+		Username:    username,
+		Rating:      playerRating,
+		Rules:       rules,
+		TimeControl: tc,
+		BotWait:     time.Duration(s.cfg.BotWaitSeconds) * time.Second,
+		Bot:         game.PlayerInfo{Username: "bot", IsBot: true, Rating: difficulty.Rating(), Difficulty: difficulty},
+		Queued: func() {
+			queuedAt = time.Now()
+			s.produceEvent(context.Background(), "", analytics.QueueEntered{Player: username, Rating: playerRating})
+		},
+	}
+	return func(ctx context.Context) (*game.Game, int, bool, error) {
+		g, idx, existing, err := s.manager.WaitForMatch(ctx, req)
+		switch {
+		case queuedAt.IsZero():
+		case err != nil:
+			// Only ctx ending fails a wait that has started.
+			s.queueAbandoned(username, time.Since(queuedAt))
+		default:
+			s.matchMade(g, username, time.Since(queuedAt))
+		}
+		return g, idx, existing, err
+	}, 0, nil
+}
 
-	s.produceEvent(context.Background(), "joined", g.ID, map[string]string{"player": username})
+// gameJoined tells everyone in the game that a player (re)joined and starts its clock.
+func (s *Server) gameJoined(g *game.Game, username string) {
+	state := g.Snapshot()
+	s.broadcastState(state, "")
+	s.checkpoint(state)
+	s.startClock(g)
+	s.produceEvent(context.Background(), g.ID, analytics.Joined{Player: username})
+}
 
-	go s.readLoop(client)
+// parseGameSettings reads the variant and time query parameters shared by /ws and /rooms.
+func (s *Server) parseGameSettings(r *http.Request) (game.Rules, game.TimeControl, error) {
+	rules, err := game.ParseVariant(r.URL.Query().Get("variant"))
+	if err != nil {
+		return game.Rules{}, game.TimeControl{}, err
+	}
+	timeControl := r.URL.Query().Get("time")
+	if timeControl == "" {
+		timeControl = s.cfg.TimeControl
+	}
+	tc, err := game.ParseTimeControl(timeControl)
+	if err != nil {
+		return game.Rules{}, game.TimeControl{}, err
+	}
+	return rules, tc, nil
 }
 
-func (s *Server) readLoop(c *wsClient) {
-	defer s.unregisterClient(c)
+// handleCreateRoom opens a private room and returns its invite code.
+func (s *Server) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	rules, tc, err := s.parseGameSettings(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	info, err := s.manager.CreateRoom(claims.Username, rules, tc, time.Duration(s.cfg.RoomTTLSeconds)*time.Second)
+	if err != nil {
+		log.Printf("create room: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(info)
+}
 
-	reconnectDeadline := time.After(time.Duration(s.cfg.ReconnectSeconds) * time.Second)
+// readLoop handles a player's messages from the moment the socket opens. closed is called when
+// the connection fails, which abandons a wait for a game still in progress.
+func (s *Server) readLoop(c *wsClient, closed context.CancelFunc) {
 	for {
 		var msg game.ClientMessage
 		if err := c.conn.ReadJSON(&msg); err != nil {
 			log.Printf("read error: %v", err)
+			closed()
+			if s.markClosed(c) {
+				s.unregisterClient(c)
+			}
 			return
 		}
 
+		g := s.clientGame(c)
+		if g == nil && msg.Type != "ping" {
+			s.clientError(c, nil, msg.Type, errNoGameYet)
+			continue
+		}
 		switch msg.Type {
 		case "move":
-			board, winner, err := c.game.ApplyMove(c.username, msg.Column)
+			if err := s.playMove(g, c.username, msg.Kind, msg.Column); err != nil {
+				s.clientError(c, g, msg.Type, err)
+			}
+
+		case "resign":
+			state, err := g.Resign(c.username)
 			if err != nil {
-				_ = c.conn.WriteJSON(game.ServerMessage{Type: "error", Error: err.Error()})
+				s.clientError(c, g, msg.Type, err)
 				continue
 			}
+			s.broadcastState(state, c.username+" resigned")
+			s.finishIfDone(state)
 
-			state := c.game.Snapshot()
-			s.broadcastState(&state, "")
-			if winner != 0 || board.IsFull() {
-				winnerName := ""
-				if winner != 0 {
-					winnerName = c.game.Players[winner-1].Username
-				}
-				s.persistFinish(state, winnerName)
-				s.produceEvent(context.Background(), "finished", state.ID, map[string]interface{}{"winner": winnerName, "moves": state.Moves})
+		case "offer_draw":
+			if err := g.OfferDraw(c.username); err != nil {
+				s.clientError(c, g, msg.Type, err)
+				continue
+			}
+			opp := opponentName(g, c.username)
+			if opp == "bot" {
+				// The bot plays on; decline on its behalf straight away.
+				_ = g.DeclineDraw(opp)
+				_ = c.send(game.ServerMessage{Type: "draw_declined", GameID: g.ID, Message: opp + " declined the draw"})
+				continue
+			}
+			s.notify(g.ID, opp, game.ServerMessage{Type: "draw_offer", GameID: g.ID, Message: c.username + " offers a draw"})
+
+		case "accept_draw":
+			state, err := g.AcceptDraw(c.username)
+			if err != nil {
+				s.clientError(c, g, msg.Type, err)
+				continue
 			}
+			s.broadcastState(state, "draw agreed")
+			s.finishIfDone(state)
 
-			// Bot move when needed
-			opp := opponentName(c.game, c.username)
-			if opp == "bot" && !c.game.Done && c.game.CurrentPlayer().IsBot {
-				s.doBotMove(c.game)
+		case "decline_draw":
+			if err := g.DeclineDraw(c.username); err != nil {
+				s.clientError(c, g, msg.Type, err)
+				continue
 			}
+			s.notify(g.ID, opponentName(g, c.username), game.ServerMessage{Type: "draw_declined", GameID: g.ID, Message: c.username + " declined the draw"})
+
+		case "rematch":
+			s.requestRematch(c, g)
 
 		case "ping":
-			_ = c.conn.WriteJSON(game.ServerMessage{Type: "pong"})
+			_ = c.send(game.ServerMessage{Type: "pong"})
 		case "reconnect":
-			reconnectDeadline = time.After(time.Duration(s.cfg.ReconnectSeconds) * time.Second)
+			// Reconnects are handled by dialing /ws again; nothing to do on a live socket.
 		default:
-			_ = c.conn.WriteJSON(game.ServerMessage{Type: "error", Error: "unknown message"})
+			s.clientError(c, g, "message", errors.New("unknown message"))
+		}
+	}
+}
+
+// playMove applies a player's move and everything that follows it: the broadcast, the
+// checkpoint, saving a finished game and the bot's reply. WebSocket and REST moves both use it.
+func (s *Server) playMove(g *game.Game, username string, kind game.MoveKind, col int) error {
+	if _, _, err := g.ApplyMove(username, kind, col); err != nil {
+		if errors.Is(err, game.ErrOutOfTime) {
+			state := g.Snapshot()
+			s.broadcastState(state, "timeout")
+			s.finishIfDone(state)
 		}
+		return err
 	}
 
-	<-reconnectDeadline
+	state := g.Snapshot()
+	s.movePlayed(state)
+	s.broadcastState(state, "")
+	s.checkpoint(state)
+	s.finishIfDone(state)
+
+	// The bot replies in the background so this WebSocket or REST caller is not held up by the search.
+	if !state.Done && state.CurrentPlayer().IsBot {
+		go s.doBotMove(g)
+	}
+	return nil
 }
 
+// doBotMove searches for the bot's reply on a snapshot of the board, without holding the game
+// lock, and applies it once the search returns. In a timed game the search must finish within
+// half of the bot's remaining clock. The game may have ended meanwhile, by resignation, forfeit
+// or the bot's flag falling; the reply is then dropped.
 func (s *Server) doBotMove(g *game.Game) {
-	bot := game.NewBot(g.PlayerIndex("bot"), g.PlayerIndex(opponentName(g, "bot")), nil)
-	col := bot.ChooseMove(g.Board)
-	g.ApplyMove("bot", col)
+	botInfo, _ := g.Bot()
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	bot := game.NewBotForDifficulty(g.PlayerIndex("bot"), g.PlayerIndex(opponentName(g, "bot")), botInfo.Difficulty, rng)
+	ctx := context.Background()
+	if left, timed := g.TimeLeft(); timed {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, left/2)
+		defer cancel()
+	}
+	move, ok := bot.ChooseMove(ctx, g.Snapshot().Board)
+	if !ok {
+		return
+	}
+	if _, _, err := g.ApplyMove("bot", move.Kind, move.Column); err != nil {
+		switch {
+		case errors.Is(err, game.ErrOutOfTime):
+			state := g.Snapshot()
+			s.broadcastState(state, "timeout")
+			s.finishIfDone(state)
+		case !errors.Is(err, game.ErrGameOver):
+			log.Printf("bot move: %v", err)
+		}
+		return
+	}
 	state := g.Snapshot()
-	s.produceEvent(context.Background(), "bot_move", g.ID, map[string]interface{}{"column": col})
-	s.broadcastState(&state, "")
+	s.movePlayed(state)
+	s.produceEvent(context.Background(), g.ID, analytics.BotMove{Column: move.Column, Kind: moveKind(move.Kind), Difficulty: string(botInfo.Difficulty)})
+	s.broadcastState(state, "")
+	s.checkpoint(state)
+	s.finishIfDone(state)
+}
+
+// finishIfDone persists a game that just ended on a move along with its finished event.
+func (s *Server) finishIfDone(state *game.Game) {
+	if !state.Done {
+		return
+	}
+	winnerName := ""
+	if state.Winner != 0 {
+		winnerName = state.Players[state.Winner-1].Username
+	}
+	s.persistFinish(state, winnerName)
+}
+
+// startClock runs one flag-fall watcher per timed game. Moves reset the running clock, so the
+// watcher polls rather than sleeping until the current deadline.
+func (s *Server) startClock(g *game.Game) {
+	if !g.TimeControl.Enabled() {
+		return
+	}
+	s.mu.Lock()
+	if s.clocks[g.ID] {
+		s.mu.Unlock()
+		return
+	}
+	s.clocks[g.ID] = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.clocks, g.ID)
+			s.mu.Unlock()
+		}()
+		for {
+			left, running := g.TimeLeft()
+			if !running {
+				return
+			}
+			time.Sleep(min(left, clockPollInterval))
+			if state, flagged := g.CheckFlag(); flagged {
+				s.broadcastState(state, "timeout")
+				s.finishIfDone(state)
+				return
+			}
+		}
+	}()
 }
 
-func (s *Server) persistFinish(state game.Game, winner string) {
+// persistFinish saves the finished game. Its finished event goes through the outbox rather than
+// straight to Kafka, so it is written with the game and survives a Kafka outage.
+func (s *Server) persistFinish(state *game.Game, winner string) {
 	ctx := context.Background()
 	movesBytes, _ := json.Marshal(state.Moves)
+	rulesBytes, _ := json.Marshal(state.Rules)
+	botInfo, hasBot := state.Bot()
 	rec := storage.FinishedGame{
-		ID:         state.ID,
-		Player1:    state.Players[0].Username,
-		Player2:    state.Players[1].Username,
-		Winner:     winner,
-		Moves:      movesBytes,
-		CreatedAt:  state.CreatedAt,
-		FinishedAt: state.UpdatedAt,
+		ID:            state.ID,
+		Player1:       state.Players[0].Username,
+		Player2:       state.Players[1].Username,
+		Winner:        winner,
+		Termination:   string(state.Termination),
+		BotDifficulty: string(botInfo.Difficulty),
+		Rules:         rulesBytes,
+		Moves:         movesBytes,
+		CreatedAt:     state.CreatedAt,
+		FinishedAt:    state.UpdatedAt,
+	}
+	if hasBot {
+		rec.Bot = botInfo.Username
+		rec.BotRating = float64(botInfo.Rating)
+	}
+	if s.relay != nil {
+		event, err := analytics.Encode(analytics.NewEvent(state.ID, analytics.Finished{
+			Winner:      winner,
+			Termination: string(state.Termination),
+			Moves:       analyticsMoves(state.Moves),
+		}))
+		if err != nil {
+			log.Printf("analytics finished event: %v", err)
+		} else {
+			rec.Events = append(rec.Events, event)
+		}
 	}
 	if err := s.repo.SaveFinishedGame(ctx, rec); err != nil {
 		log.Printf("persist finish: %v", err)
+	} else if s.relay != nil {
+		s.relay.Notify()
 	}
 	s.manager.Finish(state.ID)
+	s.mu.Lock()
+	for _, p := range s.rest[state.ID] {
+		if p.forfeit != nil {
+			p.forfeit.Stop()
+		}
+	}
+	delete(s.rest, state.ID)
+	s.mu.Unlock()
 }
 
-func (s *Server) produceEvent(ctx context.Context, eventType, gameID string, payload interface{}) {
-	if s.producer == nil {
+func (s *Server) produceEvent(ctx context.Context, gameID string, payload analytics.Payload) {
+	if s.sink == nil {
 		return
 	}
-	err := s.producer.Emit(ctx, analytics.Event{Type: eventType, GameID: gameID, Payload: payload, OccurredAt: time.Now()})
-	if err != nil {
+	err := s.sink.Emit(ctx, analytics.NewEvent(gameID, payload))
+	// A full Kafka queue is counted in the sink's stats; logging every dropped event would flood the log.
+	if err != nil && !errors.Is(err, analytics.ErrQueueFull) {
 		log.Printf("analytics emit: %v", err)
 	}
 }
 
+func analyticsMoves(moves []game.Move) []analytics.Move {
+	result := make([]analytics.Move, len(moves))
+	for i, m := range moves {
+		result[i] = analytics.Move{Column: m.Column, Kind: moveKind(m.Kind), By: m.By}
+	}
+	return result
+}
+
+// moveKind spells out the drop that an empty kind means in older move lists.
+func moveKind(kind game.MoveKind) string {
+	if kind == "" {
+		return string(game.MoveDrop)
+	}
+	return string(kind)
+}
+
+// clientGame returns the game a connection currently plays or watches.
+func (s *Server) clientGame(c *wsClient) *game.Game {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return c.game
+}
+
 func opponentName(g *game.Game, username string) string {
 	if g.Players[0].Username == username {
 		return g.Players[1].Username
@@ -196,9 +542,15 @@ func opponentName(g *game.Game, username string) string {
 	return g.Players[0].Username
 }
 
-func (s *Server) registerClient(c *wsClient) {
+// registerClient attaches c to its game as the player's connection. It reports false, leaving c
+// unattached, if the connection has already closed.
+func (s *Server) registerClient(c *wsClient, g *game.Game, player int) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.game, c.player = g, player
 	if _, ok := s.clients[c.game.ID]; !ok {
 		s.clients[c.game.ID] = make(map[string]*wsClient)
 	}
@@ -207,54 +559,116 @@ func (s *Server) registerClient(c *wsClient) {
 		existing.conn.Close()
 	}
 	s.clients[c.game.ID][c.username] = c
+	return true
 }
 
-func (s *Server) unregisterClient(c *wsClient) {
+// markClosed records that c's connection failed and reports whether c was registered with a game.
+func (s *Server) markClosed(c *wsClient) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if gameClients, ok := s.clients[c.game.ID]; ok {
+	c.closed = true
+	return c.game != nil
+}
+
+func (s *Server) unregisterClient(c *wsClient) {
+	s.mu.Lock()
+	g := c.game
+	// A connection replaced by a newer one for the same player is not a disconnect.
+	dropped := false
+	if gameClients, ok := s.clients[g.ID]; ok && gameClients[c.username] == c {
 		delete(gameClients, c.username)
 		if len(gameClients) == 0 {
-			delete(s.clients, c.game.ID)
+			delete(s.clients, g.ID)
 		}
+		dropped = true
 	}
 	c.conn.Close()
+	s.mu.Unlock()
 
+	if dropped && !g.IsDone() {
+		s.produceEvent(context.Background(), g.ID, analytics.Disconnected{Player: c.username})
+	}
 	// If opponent remains and player does not reconnect within window, forfeit.
-	go s.maybeForfeit(c.game, c.username)
+	go s.maybeForfeit(g, c.username)
+}
+
+// handleAnalyticsStats reports how many analytics events the sinks have lost, so a full queue or
+// a failing broker shows up before shutdown.
+func (s *Server) handleAnalyticsStats(w http.ResponseWriter, r *http.Request) {
+	stats, ok := analytics.StatsOf(s.sink)
+	if !ok {
+		http.Error(w, "no analytics sink keeps stats", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
 }
 
 func (s *Server) maybeForfeit(g *game.Game, username string) {
 	time.Sleep(time.Duration(s.cfg.ReconnectSeconds) * time.Second)
+	s.forfeitIfGone(g, username)
+}
+
+// forfeitIfGone ends the game as a forfeit by username unless they are connected again.
+func (s *Server) forfeitIfGone(g *game.Game, username string) {
 	if s.isConnected(g.ID, username) {
 		return
 	}
-	if g.Done {
+	if g.IsDone() {
 		return
 	}
 	state, opponent := g.Forfeit(username)
-	s.persistFinish(state, opponent)
-	s.broadcastState(&state, "forfeit")
+	if opponent == "" {
+		return
+	}
+	s.produceEvent(context.Background(), g.ID, analytics.Forfeit{Player: username, Winner: opponent, GraceSeconds: s.cfg.ReconnectSeconds})
+	s.broadcastState(state, "forfeit")
+	s.finishIfDone(state)
 }
 
+// isConnected reports whether the player has a WebSocket open on the game, or is playing it over
+// REST: polling right now or seen within the reconnect window.
 func (s *Server) isConnected(gameID, username string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if gameClients, ok := s.clients[gameID]; ok {
-		_, present := gameClients[username]
-		return present
+	if _, present := s.clients[gameID][username]; present {
+		return true
+	}
+	if p := s.rest[gameID][username]; p != nil {
+		return p.polls > 0 || time.Since(p.lastSeen) < time.Duration(s.cfg.ReconnectSeconds)*time.Second
 	}
 	return false
 }
 
+// notify sends a message to one player of a game if they are connected.
+func (s *Server) notify(gameID, username string, msg game.ServerMessage) {
+	s.mu.Lock()
+	cl := s.clients[gameID][username]
+	s.mu.Unlock()
+	if cl != nil {
+		_ = cl.send(msg)
+	}
+}
+
+// broadcastState sends the state to both players and every spectator of the game.
 func (s *Server) broadcastState(state *game.Game, message string) {
 	s.mu.Lock()
-	clients := s.clients[state.ID]
+	players := make([]*wsClient, 0, len(s.clients[state.ID]))
+	for _, cl := range s.clients[state.ID] {
+		players = append(players, cl)
+	}
+	watchers := make([]*wsClient, 0, len(s.spectators[state.ID]))
+	for cl := range s.spectators[state.ID] {
+		watchers = append(watchers, cl)
+	}
 	s.mu.Unlock()
+
 	current := state.CurrentPlayer().Username
-	for _, cl := range clients {
+	for _, cl := range players {
 		yourTurn := current == cl.username
-		msg := game.ServerMessage{Type: "state", GameID: state.ID, State: state, YourTurn: yourTurn, Opponent: opponentName(state, cl.username), Message: message}
-		_ = cl.conn.WriteJSON(msg)
+		msg := game.ServerMessage{Type: "state", GameID: state.ID, State: state, YourTurn: yourTurn, Opponent: opponentName(state, cl.username), Spectators: len(watchers), Message: message}
+		_ = cl.send(msg)
+	}
+	for _, cl := range watchers {
+		_ = cl.send(game.ServerMessage{Type: "state", GameID: state.ID, State: state, Spectators: len(watchers), Message: message})
 	}
 }
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/game"
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/storage"
+)
+
+// defaultWinRateMinGames keeps players with a handful of lucky games off the win rate board.
+const defaultWinRateMinGames = 10
+
+type leaderboardResponse struct {
+	Period   string `json:"period"`
+	Metric   string `json:"metric"`
+	MinGames int    `json:"minGames"`
+	Limit    int    `json:"limit"`
+	Offset   int    `json:"offset"`
+	storage.LeaderboardPage
+}
+
+// handleLeaderboard ranks players over ?period=daily|weekly|monthly|all by
+// ?metric=wins|winrate|rating, paged with ?limit= and ?offset=. Callers who send their session
+// token also get their own rank in "self".
+func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	period := q.Get("period")
+	if period == "" {
+		period = "all"
+	}
+	since, err := periodStart(period, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := storage.LeaderboardQuery{Metric: q.Get("metric"), Since: since, BotDifficulty: q.Get("difficulty")}
+	if query.Metric == "" {
+		query.Metric = storage.MetricWins
+	}
+	if query.BotDifficulty != "" {
+		if _, err := game.ParseDifficulty(query.BotDifficulty); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("excludeBots"); v != "" {
+		if query.ExcludeBots, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "excludeBots must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+	if query.ExcludeBots && query.BotDifficulty != "" {
+		http.Error(w, "difficulty only counts bot games and cannot be combined with excludeBots", http.StatusBadRequest)
+		return
+	}
+	query.MinGames = 1
+	if query.Metric == storage.MetricWinRate {
+		query.MinGames = defaultWinRateMinGames
+	}
+	if v := q.Get("minGames"); v != "" {
+		if query.MinGames, err = strconv.Atoi(v); err != nil || query.MinGames < 1 {
+			http.Error(w, "minGames must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	if query.Limit, query.Offset, err = parsePage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if claims, err := s.authenticate(r); err == nil {
+		query.Username = claims.Username
+	}
+
+	page, err := s.repo.Leaderboard(r.Context(), query)
+	if errors.Is(err, storage.ErrBadMetric) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("leaderboard error: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, leaderboardResponse{
+		Period:          period,
+		Metric:          query.Metric,
+		MinGames:        query.MinGames,
+		Limit:           query.Limit,
+		Offset:          query.Offset,
+		LeaderboardPage: page,
+	})
+}
+
+// periodStart returns when the current calendar day, ISO week or month began in UTC, or the zero
+// time for all-time rankings.
+func periodStart(period string, now time.Time) (time.Time, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case "all":
+		return time.Time{}, nil
+	case "daily":
+		return today, nil
+	case "weekly":
+		// Weeks start on Monday.
+		return today.AddDate(0, 0, -(int(today.Weekday())+6)%7), nil
+	case "monthly":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, errors.New("period must be daily, weekly, monthly or all")
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/game"
+)
+
+func (s *Server) handleLobby(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.manager.Lobby())
+}
+
+// handleLobbyWS pushes the lobby listing to the client now and after every change.
+func (s *Server) handleLobbyWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("upgrade: %v", err)
+		return
+	}
+	c := &wsClient{conn: conn}
+	changes, unsubscribe := s.manager.Subscribe()
+	defer unsubscribe()
+	defer conn.Close()
+
+	// The lobby channel is push-only; reading just notices the client going away.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var msg game.ClientMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg.Type == "ping" {
+				_ = c.send(game.ServerMessage{Type: "pong"})
+			}
+		}
+	}()
+
+	for {
+		lobby := s.manager.Lobby()
+		if err := c.send(game.ServerMessage{Type: "lobby", Lobby: &lobby}); err != nil {
+			return
+		}
+		select {
+		case <-changes:
+		case <-closed:
+			return
+		}
+	}
+}
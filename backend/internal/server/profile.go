@@ -0,0 +1,32 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/storage"
+)
+
+// handleProfile reports a player's rating and statistics over all their finished games.
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	username := r.PathValue("username")
+	stats, err := s.repo.PlayerStats(r.Context(), username)
+	if err != nil {
+		log.Printf("player stats: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if stats.Games == 0 {
+		// Players who never finished a game only have a profile if they hold an account.
+		if _, err := s.repo.Player(r.Context(), username); errors.Is(err, storage.ErrPlayerNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("player lookup: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
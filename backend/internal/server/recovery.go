@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/game"
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/storage"
+)
+
+// checkpoint stores the state of a game in progress so it can be resumed after a restart.
+func (s *Server) checkpoint(state *game.Game) {
+	if state.Done {
+		return
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("checkpoint %s: %v", state.ID, err)
+		return
+	}
+	rec := storage.ActiveGame{ID: state.ID, State: data, Moves: len(state.Moves), UpdatedAt: state.UpdatedAt}
+	if err := s.repo.SaveActiveGame(context.Background(), rec); err != nil {
+		log.Printf("checkpoint %s: %v", state.ID, err)
+	}
+}
+
+// RestoreGames reloads the games that were in progress when the server stopped. Players rejoin
+// them by connecting to /ws again or by any REST request for the game; anyone who does not come
+// back within the reconnect window forfeits as if they had disconnected.
+func (s *Server) RestoreGames(ctx context.Context) error {
+	saved, err := s.repo.ActiveGames(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rec := range saved {
+		g, err := game.RestoreGame(rec.State)
+		if err != nil {
+			log.Printf("restore %s: %v", rec.ID, err)
+			if err := s.repo.DeleteActiveGame(ctx, rec.ID); err != nil {
+				log.Printf("restore %s: %v", rec.ID, err)
+			}
+			continue
+		}
+		s.manager.Restore(g)
+		s.startClock(g)
+		for _, p := range g.Players {
+			if !p.IsBot {
+				go s.maybeForfeit(g, p.Username)
+			}
+		}
+		if g.Snapshot().CurrentPlayer().IsBot {
+			go s.doBotMove(g)
+		}
+	}
+	if len(saved) > 0 {
+		log.Printf("restored %d games in progress", len(saved))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/analytics"
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/game"
+)
+
+// requestRematch handles a "rematch" message on a finished game. The bot always accepts; a human
+// opponent is told about the request and has to send "rematch" too.
+func (s *Server) requestRematch(c *wsClient, prev *game.Game) {
+	window := time.Duration(s.cfg.RematchSeconds) * time.Second
+	next, err := s.manager.RequestRematch(prev, c.username, window)
+	if err != nil {
+		s.clientError(c, prev, "rematch", err)
+		return
+	}
+	opp := opponentName(prev, c.username)
+	if next == nil {
+		if bot, ok := prev.Bot(); ok {
+			next, err = s.manager.RequestRematch(prev, bot.Username, window)
+			if err != nil {
+				s.clientError(c, prev, "rematch", err)
+				return
+			}
+		} else {
+			s.notify(prev.ID, opp, game.ServerMessage{Type: "rematch_offer", GameID: prev.ID, Message: c.username + " wants a rematch"})
+			return
+		}
+	}
+	s.startRematch(prev, next)
+}
+
+// startRematch moves the players and spectators of the finished game over to its rematch.
+func (s *Server) startRematch(prev, next *game.Game) {
+	s.mu.Lock()
+	if players, ok := s.clients[prev.ID]; ok {
+		delete(s.clients, prev.ID)
+		s.clients[next.ID] = players
+		for _, cl := range players {
+			cl.game = next
+			cl.player = next.PlayerIndex(cl.username)
+		}
+	}
+	if watchers, ok := s.spectators[prev.ID]; ok {
+		delete(s.spectators, prev.ID)
+		s.spectators[next.ID] = watchers
+		for cl := range watchers {
+			cl.game = next
+		}
+	}
+	s.mu.Unlock()
+
+	state := next.Snapshot()
+	s.broadcastState(state, "rematch")
+	s.checkpoint(state)
+	s.startClock(next)
+	s.produceEvent(context.Background(), next.ID, analytics.Rematch{PreviousGameID: prev.ID})
+	if state.CurrentPlayer().IsBot {
+		go s.doBotMove(next)
+	}
+}
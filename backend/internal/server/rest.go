@@ -0,0 +1,231 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/game"
+)
+
+const (
+	defaultPollTimeout = 30 * time.Second
+	maxPollTimeout     = 60 * time.Second
+)
+
+// restPresence is what a REST player has instead of a connection: when they last made a request
+// for their game, and how many of their long polls are waiting on it.
+type restPresence struct {
+	lastSeen time.Time
+	polls    int
+	// forfeit runs the presence check once the reconnect window has passed since the last
+	// request, as unregisterClient does when a WebSocket closes.
+	forfeit *time.Timer
+}
+
+// restSeen records a request by username for the game, if they play in it, so isConnected does
+// not forfeit them. A poll counts as present until the returned func is called. Each request,
+// or poll once it ends, restarts the timer that forfeits a player who stops coming back.
+func (s *Server) restSeen(g *game.Game, username string, poll bool) (done func()) {
+	if g.PlayerIndex(username) == 0 {
+		return func() {}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// persistFinish has dropped, or is about to drop, the game's entries; don't recreate them.
+	if g.IsDone() {
+		return func() {}
+	}
+	players, ok := s.rest[g.ID]
+	if !ok {
+		players = make(map[string]*restPresence)
+		s.rest[g.ID] = players
+	}
+	p, ok := players[username]
+	if !ok {
+		p = &restPresence{}
+		players[username] = p
+	}
+	p.lastSeen = time.Now()
+	if !poll {
+		s.armForfeitLocked(g, username, p)
+		return func() {}
+	}
+	p.polls++
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		p.polls--
+		p.lastSeen = time.Now()
+		if !g.IsDone() {
+			s.armForfeitLocked(g, username, p)
+		}
+	}
+}
+
+func (s *Server) armForfeitLocked(g *game.Game, username string, p *restPresence) {
+	grace := time.Duration(s.cfg.ReconnectSeconds) * time.Second
+	if p.forfeit == nil {
+		p.forfeit = time.AfterFunc(grace, func() { s.forfeitIfGone(g, username) })
+		return
+	}
+	p.forfeit.Reset(grace)
+}
+
+// handleCreateGame is the REST counterpart of connecting to /ws: it accepts the same query
+// parameters and blocks until the player is in a game.
+func (s *Server) handleCreateGame(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	join, status, err := s.joiner(r, claims.Username)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	// The request's context ends when the client goes away, taking them out of the queue.
+	g, _, existing, err := join(r.Context())
+	if err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		s.reportError(nil, claims.Username, "join", err)
+		http.Error(w, err.Error(), gameErrorStatus(err))
+		return
+	}
+	s.restSeen(g, claims.Username, false)
+	s.gameJoined(g, claims.Username)
+
+	msg := s.stateFor(g.Snapshot(), claims.Username)
+	msg.Reconnect = existing
+	status = http.StatusCreated
+	if existing {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, msg)
+}
+
+// handleGetGame returns the current state of an active or recently finished game. Players who
+// send their session token also get their turn flag and opponent.
+func (s *Server) handleGetGame(w http.ResponseWriter, r *http.Request) {
+	g := s.manager.Game(r.PathValue("id"))
+	if g == nil {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	claims, _ := s.authenticate(r)
+	s.restSeen(g, claims.Username, false)
+	writeJSON(w, http.StatusOK, s.stateFor(g.Snapshot(), claims.Username))
+}
+
+// handleSubmitMove plays a move sent as {"column": 3, "kind": "drop"} and returns the resulting
+// state. A bot replies in the background; clients long-poll handleWaitGame for its move.
+func (s *Server) handleSubmitMove(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	g := s.manager.Game(r.PathValue("id"))
+	if g == nil {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	s.restSeen(g, claims.Username, false)
+	var msg game.ClientMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := s.playMove(g, claims.Username, msg.Kind, msg.Column); err != nil {
+		s.reportError(g, claims.Username, "move", err)
+		http.Error(w, err.Error(), gameErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.stateFor(g.Snapshot(), claims.Username))
+}
+
+// handleWaitGame long-polls: it returns as soon as the game's version is above ?version=, or
+// with the unchanged state once ?timeout= seconds (default 30, at most 60) have passed.
+func (s *Server) handleWaitGame(w http.ResponseWriter, r *http.Request) {
+	g := s.manager.Game(r.PathValue("id"))
+	if g == nil {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	version, err := strconv.Atoi(r.URL.Query().Get("version"))
+	if err != nil {
+		http.Error(w, "version must be the last state version seen", http.StatusBadRequest)
+		return
+	}
+	timeout := defaultPollTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			http.Error(w, "timeout must be a number of seconds", http.StatusBadRequest)
+			return
+		}
+		timeout = min(time.Duration(seconds)*time.Second, maxPollTimeout)
+	}
+	claims, _ := s.authenticate(r)
+	defer s.restSeen(g, claims.Username, true)()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		// Take the channel before the snapshot so a change between the two is not missed.
+		changed := g.Changed()
+		state := g.Snapshot()
+		if state.Version > version {
+			writeJSON(w, http.StatusOK, s.stateFor(state, claims.Username))
+			return
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			writeJSON(w, http.StatusOK, s.stateFor(state, claims.Username))
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// stateFor builds the same state message a WebSocket client of username would receive.
+func (s *Server) stateFor(state *game.Game, username string) game.ServerMessage {
+	msg := game.ServerMessage{Type: "state", GameID: state.ID, State: state}
+	if state.PlayerIndex(username) != 0 {
+		msg.YourTurn = !state.Done && state.CurrentPlayer().Username == username
+		msg.Opponent = opponentName(state, username)
+	}
+	s.mu.Lock()
+	msg.Spectators = len(s.spectators[state.ID])
+	s.mu.Unlock()
+	return msg
+}
+
+// gameErrorStatus maps errors from joining or playing a game to HTTP statuses.
+func gameErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, game.ErrNotYourGame):
+		return http.StatusForbidden
+	case errors.Is(err, game.ErrRoomNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, game.ErrRoomExpired):
+		return http.StatusGone
+	case errors.Is(err, game.ErrNotYourTurn), errors.Is(err, game.ErrGameOver),
+		errors.Is(err, game.ErrOutOfTime), errors.Is(err, game.ErrAlreadyWaiting):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/game"
+)
+
+// handleSpectate streams a live game's state to a read-only watcher.
+func (s *Server) handleSpectate(w http.ResponseWriter, r *http.Request) {
+	g := s.manager.ActiveGame(r.URL.Query().Get("gameId"))
+	if g == nil {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("upgrade: %v", err)
+		return
+	}
+	// Watching needs no account; a valid session only labels the watcher.
+	claims, _ := s.authenticate(r)
+	watcher := &wsClient{username: claims.Username, conn: conn, game: g}
+	s.addSpectator(watcher)
+	s.broadcastState(g.Snapshot(), "")
+
+	go s.spectatorLoop(watcher)
+}
+
+// spectatorLoop answers pings and rejects anything that would change the game.
+func (s *Server) spectatorLoop(c *wsClient) {
+	defer s.removeSpectator(c)
+	for {
+		var msg game.ClientMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case "ping":
+			_ = c.send(game.ServerMessage{Type: "pong"})
+		default:
+			_ = c.send(game.ServerMessage{Type: "error", Error: "spectators cannot " + msg.Type})
+		}
+	}
+}
+
+func (s *Server) addSpectator(c *wsClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.spectators[c.game.ID]; !ok {
+		s.spectators[c.game.ID] = make(map[*wsClient]struct{})
+	}
+	s.spectators[c.game.ID][c] = struct{}{}
+}
+
+func (s *Server) removeSpectator(c *wsClient) {
+	s.mu.Lock()
+	g := c.game
+	if watchers, ok := s.spectators[g.ID]; ok {
+		delete(watchers, c)
+		if len(watchers) == 0 {
+			delete(s.spectators, g.ID)
+		}
+	}
+	s.mu.Unlock()
+	c.conn.Close()
+	s.broadcastState(g.Snapshot(), "")
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// ActiveGame is the latest snapshot of a game still in progress, kept so it survives a restart.
+type ActiveGame struct {
+	ID        string
+	State     json.RawMessage
+	Moves     int // snapshots with fewer moves never overwrite newer ones
+	UpdatedAt time.Time
+}
+
+// SaveActiveGame upserts the snapshot unless a newer one is stored or the game already finished.
+func (r *Repository) SaveActiveGame(ctx context.Context, g ActiveGame) error {
+	_, err := r.pool.Exec(ctx, `
+INSERT INTO active_games (id, state, moves, updated_at)
+SELECT $1::text, $2::jsonb, $3::int, $4::timestamptz
+WHERE NOT EXISTS (SELECT 1 FROM games WHERE id = $1)
+ON CONFLICT (id) DO UPDATE SET state = EXCLUDED.state, moves = EXCLUDED.moves, updated_at = EXCLUDED.updated_at
+WHERE active_games.moves <= EXCLUDED.moves;
+`, g.ID, g.State, g.Moves, g.UpdatedAt)
+	return err
+}
+
+// ActiveGames returns every stored in-progress game.
+func (r *Repository) ActiveGames(ctx context.Context) ([]ActiveGame, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id, state, moves, updated_at FROM active_games`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []ActiveGame
+	for rows.Next() {
+		var g ActiveGame
+		if err := rows.Scan(&g.ID, &g.State, &g.Moves, &g.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, g)
+	}
+	return result, rows.Err()
+}
+
+// DeleteActiveGame drops a snapshot that can no longer be resumed.
+func (r *Repository) DeleteActiveGame(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM active_games WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrGameNotFound = errors.New("game not found")
+
+// Results a game listing can be filtered by, from the listed player's point of view.
+const (
+	ResultWin  = "win"
+	ResultLoss = "loss"
+	ResultDraw = "draw"
+)
+
+// GameFilter selects a page of one player's finished games, newest first. Zero values of the
+// optional fields match everything.
+type GameFilter struct {
+	Username string
+	Opponent string
+	Result   string    // ResultWin, ResultLoss or ResultDraw
+	From, To time.Time // finished at or after From, before To
+	Limit    int
+	Offset   int
+}
+
+// playerGamesWhere matches GameFilter; $1-$5 are username, opponent, result, from and to.
+const playerGamesWhere = `
+WHERE (player1 = $1 OR player2 = $1)
+  AND ($2 = '' OR (player1 = $1 AND player2 = $2) OR (player2 = $1 AND player1 = $2))
+  AND ($3 = ''
+    OR ($3 = 'win' AND winner = $1)
+    OR ($3 = 'loss' AND COALESCE(winner, '') NOT IN ('', $1))
+    OR ($3 = 'draw' AND COALESCE(winner, '') = ''))
+  AND ($4::timestamptz IS NULL OR finished_at >= $4)
+  AND ($5::timestamptz IS NULL OR finished_at < $5)`
+
+// PlayerGames lists the player's games without their moves, along with the number of games
+// matching the filter across all pages.
+func (r *Repository) PlayerGames(ctx context.Context, f GameFilter) ([]FinishedGame, int, error) {
+	args := []any{f.Username, f.Opponent, f.Result, optionalTime(f.From), optionalTime(f.To)}
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM games`+playerGamesWhere, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.pool.Query(ctx, `
+SELECT id, player1, player2, COALESCE(winner, ''), termination, bot_difficulty, rules, created_at, finished_at
+FROM games`+playerGamesWhere+`
+ORDER BY finished_at DESC, id
+LIMIT $6 OFFSET $7`, append(args, f.Limit, f.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	result := []FinishedGame{}
+	for rows.Next() {
+		var g FinishedGame
+		err := rows.Scan(&g.ID, &g.Player1, &g.Player2, &g.Winner, &g.Termination, &g.BotDifficulty, &g.Rules, &g.CreatedAt, &g.FinishedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, g)
+	}
+	return result, total, rows.Err()
+}
+
+// FinishedGame returns a stored game with its full move list.
+func (r *Repository) FinishedGame(ctx context.Context, id string) (FinishedGame, error) {
+	g := FinishedGame{ID: id}
+	err := r.pool.QueryRow(ctx, `
+SELECT player1, player2, COALESCE(winner, ''), termination, bot_difficulty, rules, moves, created_at, finished_at
+FROM games WHERE id = $1
+`, id).Scan(&g.Player1, &g.Player2, &g.Winner, &g.Termination, &g.BotDifficulty, &g.Rules, &g.Moves, &g.CreatedAt, &g.FinishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return FinishedGame{}, ErrGameNotFound
+	}
+	return g, err
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/rating"
+)
+
+// Leaderboard ranking metrics.
+const (
+	MetricWins    = "wins"
+	MetricWinRate = "winrate"
+	MetricRating  = "rating"
+)
+
+var ErrBadMetric = errors.New("metric must be wins, winrate or rating")
+
+// LeaderboardQuery selects which games count and how players are ranked.
+type LeaderboardQuery struct {
+	Metric string
+	// Since drops games finished before it; zero counts all time.
+	Since time.Time
+	// MinGames leaves out players with fewer counted games.
+	MinGames      int
+	ExcludeBots   bool
+	BotDifficulty string // non-empty counts only games against bots of this difficulty
+	Limit, Offset int
+	// Username, if set, is looked up for LeaderboardPage.Self wherever they rank.
+	Username string
+}
+
+type LeaderboardRow struct {
+	Rank     int     `json:"rank"`
+	Username string  `json:"username"`
+	Wins     int     `json:"wins"`
+	Games    int     `json:"games"`
+	WinRate  float64 `json:"winRate"`
+	Rating   float64 `json:"rating"`
+}
+
+type LeaderboardPage struct {
+	Rows  []LeaderboardRow `json:"rows"`
+	Total int              `json:"total"`          // ranked players across all pages
+	Self  *LeaderboardRow  `json:"self,omitempty"` // the requested player, if ranked
+}
+
+// leaderboardOrder maps each metric to its ranking expression; ties share a rank.
+var leaderboardOrder = map[string]string{
+	MetricWins:    "wins DESC",
+	MetricWinRate: "win_rate DESC, games DESC",
+	MetricRating:  "rating DESC",
+}
+
+// Leaderboard ranks the players of the games matching q. Bots themselves are never ranked.
+// The rating metric ranks players active in the period by their current rating.
+func (r *Repository) Leaderboard(ctx context.Context, q LeaderboardQuery) (LeaderboardPage, error) {
+	order, ok := leaderboardOrder[q.Metric]
+	if !ok {
+		return LeaderboardPage{}, ErrBadMetric
+	}
+	ranked := fmt.Sprintf(`
+WITH counted AS (
+	SELECT player1, player2, COALESCE(winner, '') AS winner FROM games
+	WHERE ($1::timestamptz IS NULL OR finished_at >= $1)
+	  AND NOT ($2 AND `+botGame+`)
+	  AND ($3 = '' OR bot_difficulty = $3)
+), per_player AS (
+	SELECT player1 AS username, winner FROM counted
+	UNION ALL
+	SELECT player2, winner FROM counted
+), stats AS (
+	SELECT username, COUNT(*) AS games, COUNT(*) FILTER (WHERE winner = username) AS wins
+	FROM per_player
+	WHERE username <> 'bot'
+	GROUP BY username
+	HAVING COUNT(*) >= $4
+), ranked AS (
+	SELECT s.username, s.wins, s.games, s.wins::float8 / s.games AS win_rate,
+		COALESCE(r.rating, %v) AS rating
+	FROM stats s LEFT JOIN ratings r ON r.username = s.username
+)
+SELECT RANK() OVER (ORDER BY %s) AS rank, username, wins, games, win_rate, rating, COUNT(*) OVER ()
+FROM ranked`, rating.Default, order)
+	args := []any{optionalTime(q.Since), q.ExcludeBots, q.BotDifficulty, max(q.MinGames, 1)}
+
+	rows, err := r.pool.Query(ctx, `SELECT * FROM (`+ranked+`) board ORDER BY rank, username LIMIT $5 OFFSET $6`,
+		append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return LeaderboardPage{}, err
+	}
+	defer rows.Close()
+	page := LeaderboardPage{Rows: []LeaderboardRow{}}
+	for rows.Next() {
+		var row LeaderboardRow
+		if err := rows.Scan(&row.Rank, &row.Username, &row.Wins, &row.Games, &row.WinRate, &row.Rating, &page.Total); err != nil {
+			return LeaderboardPage{}, err
+		}
+		page.Rows = append(page.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return LeaderboardPage{}, err
+	}
+
+	if len(page.Rows) == 0 && q.Offset > 0 {
+		// Past the last page there is no row to carry the window count.
+		if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM (`+ranked+`) board`, args...).Scan(&page.Total); err != nil {
+			return LeaderboardPage{}, err
+		}
+	}
+	if q.Username != "" {
+		var self LeaderboardRow
+		var total int
+		err := r.pool.QueryRow(ctx, `SELECT * FROM (`+ranked+`) board WHERE username = $5`, append(args, q.Username)...).
+			Scan(&self.Rank, &self.Username, &self.Wins, &self.Games, &self.WinRate, &self.Rating, &total)
+		if err == nil {
+			page.Self = &self
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return LeaderboardPage{}, err
+		}
+	}
+	return page, nil
+}
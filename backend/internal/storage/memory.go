@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/rating"
+)
+
+// MemoryStore keeps everything in process memory and loses it on exit. It answers every query
+// the way the SQL stores do, which makes it suitable for tests and quick local runs.
+type MemoryStore struct {
+	mu      sync.Mutex
+	games   map[string]FinishedGame
+	ratings map[string]float64
+	active  map[string]ActiveGame
+	players map[string]Player // lower-cased username -> account
+	outbox  []OutboxEvent
+	nextID  int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		games:   make(map[string]FinishedGame),
+		ratings: make(map[string]float64),
+		active:  make(map[string]ActiveGame),
+		players: make(map[string]Player),
+	}
+}
+
+func (m *MemoryStore) SaveFinishedGame(ctx context.Context, g FinishedGame) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.active, g.ID)
+	if _, ok := m.games[g.ID]; ok {
+		return nil
+	}
+	m.games[g.ID] = g
+	for _, event := range g.Events {
+		m.nextID++
+		m.outbox = append(m.outbox, OutboxEvent{ID: m.nextID, GameID: g.ID, Event: event, CreatedAt: g.FinishedAt})
+	}
+
+	players := [2]string{g.Player1, g.Player2}
+	var ratings [2]float64
+	for i, name := range players {
+		ratings[i] = m.ratingLocked(name)
+		if name == g.Bot {
+			ratings[i] = g.BotRating
+		}
+	}
+	ratings[0], ratings[1] = rating.Update(ratings[0], ratings[1], gameScore(g))
+	for i, name := range players {
+		if name != g.Bot {
+			m.ratings[name] = ratings[i]
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Rating(ctx context.Context, username string) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ratingLocked(username), nil
+}
+
+func (m *MemoryStore) ratingLocked(username string) float64 {
+	if value, ok := m.ratings[username]; ok {
+		return value
+	}
+	return rating.Default
+}
+
+func (m *MemoryStore) FinishedGame(ctx context.Context, id string) (FinishedGame, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.games[id]
+	if !ok {
+		return FinishedGame{}, ErrGameNotFound
+	}
+	return g, nil
+}
+
+func (m *MemoryStore) PlayerGames(ctx context.Context, f GameFilter) ([]FinishedGame, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var matched []FinishedGame
+	for _, g := range m.playerGamesLocked(f.Username) {
+		opponent := g.Player2
+		if g.Player2 == f.Username {
+			opponent = g.Player1
+		}
+		switch {
+		case f.Opponent != "" && opponent != f.Opponent,
+			f.Result == ResultWin && g.Winner != f.Username,
+			f.Result == ResultLoss && (g.Winner == "" || g.Winner == f.Username),
+			f.Result == ResultDraw && g.Winner != "",
+			!f.From.IsZero() && g.FinishedAt.Before(f.From),
+			!f.To.IsZero() && !g.FinishedAt.Before(f.To):
+			continue
+		}
+		g.Moves = nil
+		matched = append(matched, g)
+	}
+	// Newest first, as the SQL stores list them.
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].FinishedAt.After(matched[j].FinishedAt) })
+	start := min(f.Offset, len(matched))
+	end := min(start+f.Limit, len(matched))
+	return append([]FinishedGame{}, matched[start:end]...), len(matched), nil
+}
+
+// playerGamesLocked returns the player's games oldest first.
+func (m *MemoryStore) playerGamesLocked(username string) []FinishedGame {
+	var result []FinishedGame
+	for _, g := range m.games {
+		if g.Player1 == username || g.Player2 == username {
+			result = append(result, g)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].FinishedAt.Equal(result[j].FinishedAt) {
+			return result[i].FinishedAt.Before(result[j].FinishedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+func (m *MemoryStore) PlayerStats(ctx context.Context, username string) (PlayerStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := PlayerStats{Username: username, Rating: m.ratingLocked(username)}
+	var first, second, bot [3]int // games, wins, losses
+	var moves, seconds float64
+	streak := 0
+	games := m.playerGamesLocked(username)
+	for _, g := range games {
+		won := g.Winner == username
+		lost := g.Winner != "" && !won
+		tally := func(rec *[3]int) {
+			rec[0]++
+			if won {
+				rec[1]++
+			} else if lost {
+				rec[2]++
+			}
+		}
+		if g.Player1 == username {
+			tally(&first)
+		} else {
+			tally(&second)
+		}
+		if g.Bot != "" || g.BotDifficulty != "" || g.Player1 == "bot" || g.Player2 == "bot" {
+			tally(&bot)
+		}
+		if lost && g.Termination == "forfeit" {
+			stats.Forfeits++
+		}
+		var list []json.RawMessage
+		if json.Unmarshal(g.Moves, &list) == nil {
+			moves += float64(len(list))
+		}
+		seconds += g.FinishedAt.Sub(g.CreatedAt).Seconds()
+
+		if won {
+			streak++
+			stats.LongestWinStreak = max(stats.LongestWinStreak, streak)
+		} else {
+			streak = 0
+		}
+	}
+	if len(games) > 0 {
+		stats.AverageMoves = moves / float64(len(games))
+		stats.AverageSeconds = seconds / float64(len(games))
+	}
+	stats.AsFirst = newRecord(first[0], first[1], first[2])
+	stats.AsSecond = newRecord(second[0], second[1], second[2])
+	stats.VsBot = newRecord(bot[0], bot[1], bot[2])
+	stats.Record = newRecord(first[0]+second[0], first[1]+second[1], first[2]+second[2])
+	return stats, nil
+}
+
+func (m *MemoryStore) Leaderboard(ctx context.Context, q LeaderboardQuery) (LeaderboardPage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := leaderboardOrder[q.Metric]; !ok {
+		return LeaderboardPage{}, ErrBadMetric
+	}
+
+	byPlayer := make(map[string]*LeaderboardRow)
+	for _, g := range m.games {
+		isBotGame := g.Bot != "" || g.BotDifficulty != "" || g.Player1 == "bot" || g.Player2 == "bot"
+		if (!q.Since.IsZero() && g.FinishedAt.Before(q.Since)) ||
+			(q.ExcludeBots && isBotGame) ||
+			(q.BotDifficulty != "" && g.BotDifficulty != q.BotDifficulty) {
+			continue
+		}
+		for _, name := range [2]string{g.Player1, g.Player2} {
+			if name == "bot" {
+				continue
+			}
+			row, ok := byPlayer[name]
+			if !ok {
+				row = &LeaderboardRow{Username: name, Rating: m.ratingLocked(name)}
+				byPlayer[name] = row
+			}
+			row.Games++
+			if g.Winner == name {
+				row.Wins++
+			}
+		}
+	}
+
+	rows := []LeaderboardRow{}
+	for _, row := range byPlayer {
+		if row.Games >= max(q.MinGames, 1) {
+			row.WinRate = float64(row.Wins) / float64(row.Games)
+			rows = append(rows, *row)
+		}
+	}
+	// compare orders rows like leaderboardOrder: negative when a ranks above b, 0 on a tie.
+	compare := func(a, b LeaderboardRow) float64 {
+		switch q.Metric {
+		case MetricWinRate:
+			if a.WinRate != b.WinRate {
+				return b.WinRate - a.WinRate
+			}
+			return float64(b.Games - a.Games)
+		case MetricRating:
+			return b.Rating - a.Rating
+		}
+		return float64(b.Wins - a.Wins)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if c := compare(rows[i], rows[j]); c != 0 {
+			return c < 0
+		}
+		return strings.Compare(rows[i].Username, rows[j].Username) < 0
+	})
+	for i := range rows {
+		rows[i].Rank = i + 1
+		if i > 0 && compare(rows[i-1], rows[i]) == 0 {
+			rows[i].Rank = rows[i-1].Rank
+		}
+	}
+
+	page := LeaderboardPage{Total: len(rows)}
+	start := min(q.Offset, len(rows))
+	page.Rows = append([]LeaderboardRow{}, rows[start:min(start+q.Limit, len(rows))]...)
+	for _, row := range rows {
+		if q.Username != "" && row.Username == q.Username {
+			self := row
+			page.Self = &self
+		}
+	}
+	return page, nil
+}
+
+func (m *MemoryStore) SaveActiveGame(ctx context.Context, g ActiveGame) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, finished := m.games[g.ID]; finished {
+		return nil
+	}
+	if current, ok := m.active[g.ID]; ok && current.Moves > g.Moves {
+		return nil
+	}
+	m.active[g.ID] = g
+	return nil
+}
+
+func (m *MemoryStore) ActiveGames(ctx context.Context) ([]ActiveGame, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]ActiveGame, 0, len(m.active))
+	for _, g := range m.active {
+		result = append(result, g)
+	}
+	return result, nil
+}
+
+func (m *MemoryStore) DeleteActiveGame(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.active, id)
+	return nil
+}
+
+func (m *MemoryStore) PendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]OutboxEvent{}, m.outbox[:min(limit, len(m.outbox))]...), nil
+}
+
+func (m *MemoryStore) DeleteEvents(ctx context.Context, ids []int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	remove := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	kept := m.outbox[:0]
+	for _, e := range m.outbox {
+		if !remove[e.ID] {
+			kept = append(kept, e)
+		}
+	}
+	m.outbox = kept
+	return nil
+}
+
+func (m *MemoryStore) CreatePlayer(ctx context.Context, p Player) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := strings.ToLower(p.Username)
+	if _, taken := m.players[key]; taken {
+		return ErrUsernameTaken
+	}
+	m.players[key] = p
+	return nil
+}
+
+func (m *MemoryStore) Player(ctx context.Context, username string) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.players[strings.ToLower(username)]
+	if !ok || p.Username != username {
+		return Player{}, ErrPlayerNotFound
+	}
+	return p, nil
+}
+
+func (m *MemoryStore) Close() {}
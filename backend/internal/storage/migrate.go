@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// Backends with their own migrations, kept in migrations/<dialect>.
+const (
+	dialectPostgres = "postgres"
+	dialectSQLite   = "sqlite"
+)
+
+// migrationLockID keys the advisory lock that keeps two servers from migrating at once.
+const migrationLockID = 4240001
+
+// migration is one file in migrations/<dialect>/, named <version>_<description>.sql with versions
+// numbered 1, 2, 3, ... without gaps.
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// ErrUnversioned reports a database without a schema_version table, which no migration has touched.
+var ErrUnversioned = errors.New("database is unversioned: no migrations have been applied")
+
+// ErrSchemaBehind reports migrations the database has not applied yet.
+type ErrSchemaBehind struct{ Database, Binary int }
+
+func (e ErrSchemaBehind) Error() string {
+	return fmt.Sprintf("database schema is at version %d but this binary needs %d; run the migrate command or enable MIGRATE_ON_START", e.Database, e.Binary)
+}
+
+// ErrSchemaAhead reports a database migrated by a newer binary, which this one may not understand.
+type ErrSchemaAhead struct{ Database, Binary int }
+
+func (e ErrSchemaAhead) Error() string {
+	return fmt.Sprintf("database schema is at version %d, newer than this binary's %d; deploy a newer build", e.Database, e.Binary)
+}
+
+func loadMigrations(dialect string) ([]migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []migration
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", entry.Name())
+		}
+		sql, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, migration{version: version, name: entry.Name(), sql: string(sql)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].version < result[j].version })
+	for i, m := range result {
+		if m.version != i+1 {
+			return nil, fmt.Errorf("migration %s: expected version %d", m.name, i+1)
+		}
+	}
+	return result, nil
+}
+
+// LatestSchemaVersion is the Postgres schema version this binary was built for.
+func LatestSchemaVersion() (int, error) {
+	migrations, err := loadMigrations(dialectPostgres)
+	return len(migrations), err
+}
+
+// SchemaVersion returns the newest migration applied to the database, or ErrUnversioned if none
+// ever was. It only reads.
+func (r *Repository) SchemaVersion(ctx context.Context) (int, error) {
+	var exists bool
+	if err := r.pool.QueryRow(ctx, `SELECT to_regclass('schema_version') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrUnversioned
+	}
+	var version int
+	err := r.pool.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// Migrate applies every pending migration, each in its own transaction, and returns how many
+// it applied. It refuses to touch a database that is ahead of the binary.
+func (r *Repository) Migrate(ctx context.Context) (int, error) {
+	migrations, err := loadMigrations(dialectPostgres)
+	if err != nil {
+		return 0, err
+	}
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return 0, err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if _, err := conn.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS schema_version (
+	version INT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL
+)`); err != nil {
+		return 0, err
+	}
+	current, err := r.SchemaVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if current > len(migrations) {
+		return 0, ErrSchemaAhead{Database: current, Binary: len(migrations)}
+	}
+	for _, m := range migrations[current:] {
+		err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, m.sql); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_version (version, name, applied_at) VALUES ($1, $2, $3)`, m.version, m.name, time.Now())
+			return err
+		})
+		if err != nil {
+			return m.version - current - 1, fmt.Errorf("migration %s: %w", m.name, err)
+		}
+	}
+	return len(migrations) - current, nil
+}
+
+// checkSchema fails unless the database is at exactly the binary's schema version.
+func (r *Repository) checkSchema(ctx context.Context) error {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+	current, err := r.SchemaVersion(ctx)
+	if err != nil && !errors.Is(err, ErrUnversioned) {
+		return err
+	}
+	return compareSchema(current, latest)
+}
+
+// SchemaStatus compares the schema of the store named by kind, as for Open, with this binary's
+// without changing anything: it returns nil when they match, ErrUnversioned for a database
+// never migrated, and ErrSchemaBehind or ErrSchemaAhead otherwise. A missing SQLite file is an
+// error rather than being created. The memory store has no schema and always matches.
+func SchemaStatus(ctx context.Context, kind, postgresURL, sqlitePath string) error {
+	var dialect string
+	var current int
+	var err error
+	switch kind {
+	case "postgres", "":
+		dialect = dialectPostgres
+		pool, openErr := pgxpool.New(ctx, postgresURL)
+		if openErr != nil {
+			return openErr
+		}
+		defer pool.Close()
+		current, err = (&Repository{pool: pool}).SchemaVersion(ctx)
+	case "sqlite":
+		dialect = dialectSQLite
+		db, openErr := openSQLite(sqlitePath, true)
+		if openErr != nil {
+			return openErr
+		}
+		defer db.Close()
+		current, err = (&SQLiteStore{db: db}).SchemaVersion(ctx)
+	case "memory":
+		return nil
+	default:
+		return fmt.Errorf("unknown storage %q: use postgres, sqlite or memory", kind)
+	}
+	if err != nil {
+		return err
+	}
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return err
+	}
+	return compareSchema(current, len(migrations))
+}
+
+func compareSchema(current, latest int) error {
+	switch {
+	case current > latest:
+		return ErrSchemaAhead{Database: current, Binary: latest}
+	case current < latest:
+		return ErrSchemaBehind{Database: current, Binary: latest}
+	}
+	return nil
+}
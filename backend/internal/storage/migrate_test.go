@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteSchemaStatusOnlyReads(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "game.db")
+	if err := SchemaStatus(ctx, "sqlite", "", path); err == nil {
+		t.Fatal("missing file reported as current")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("status created the file: %v", err)
+	}
+
+	// An existing file nothing has migrated.
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE unrelated (id INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	for i := 0; i < 2; i++ {
+		if err := SchemaStatus(ctx, "sqlite", "", path); !errors.Is(err, ErrUnversioned) {
+			t.Fatalf("status %d: got %v, want ErrUnversioned", i+1, err)
+		}
+	}
+	if _, err := NewSQLiteStore(ctx, path, false); !errors.As(err, &ErrSchemaBehind{}) {
+		t.Fatalf("opening without migrating: got %v, want ErrSchemaBehind", err)
+	}
+
+	store, err := NewSQLiteStore(ctx, path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	if err := SchemaStatus(ctx, "sqlite", "", path); err != nil {
+		t.Fatalf("after migrating: %v", err)
+	}
+}
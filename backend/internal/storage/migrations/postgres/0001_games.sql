@@ -0,0 +1,12 @@
+-- Finished games. Statements in migrations that predate the migration system use IF NOT EXISTS
+-- so databases created by the old inline schema adopt them without changes.
+CREATE TABLE IF NOT EXISTS games (
+	id TEXT PRIMARY KEY,
+	player1 TEXT,
+	player2 TEXT,
+	winner TEXT,
+	moves JSONB,
+	created_at TIMESTAMPTZ,
+	finished_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_games_winner ON games(winner);
@@ -0,0 +1,2 @@
+ALTER TABLE games ADD COLUMN IF NOT EXISTS bot_difficulty TEXT NOT NULL DEFAULT '';
+ALTER TABLE games ADD COLUMN IF NOT EXISTS termination TEXT NOT NULL DEFAULT '';
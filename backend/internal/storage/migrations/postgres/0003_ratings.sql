@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS ratings (
+	username TEXT PRIMARY KEY,
+	rating DOUBLE PRECISION NOT NULL,
+	games INT NOT NULL DEFAULT 0,
+	updated_at TIMESTAMPTZ NOT NULL
+);
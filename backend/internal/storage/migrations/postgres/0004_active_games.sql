@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS active_games (
+	id TEXT PRIMARY KEY,
+	state JSONB NOT NULL,
+	moves INT NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS players (
+	username TEXT PRIMARY KEY,
+	password_hash TEXT NOT NULL DEFAULT '',
+	guest BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_players_username_lower ON players(LOWER(username));
@@ -0,0 +1,3 @@
+ALTER TABLE games ADD COLUMN IF NOT EXISTS rules JSONB NOT NULL DEFAULT '{"rows":6,"columns":7,"connect":4}';
+CREATE INDEX IF NOT EXISTS idx_games_player1 ON games(player1, finished_at);
+CREATE INDEX IF NOT EXISTS idx_games_player2 ON games(player2, finished_at);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS outbox (
+	id BIGSERIAL PRIMARY KEY,
+	game_id TEXT NOT NULL,
+	event JSONB NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,38 @@
+-- Times are stored as Unix milliseconds and JSON as TEXT.
+CREATE TABLE games (
+	id TEXT PRIMARY KEY,
+	player1 TEXT NOT NULL,
+	player2 TEXT NOT NULL,
+	winner TEXT NOT NULL DEFAULT '',
+	termination TEXT NOT NULL DEFAULT '',
+	bot_difficulty TEXT NOT NULL DEFAULT '',
+	rules TEXT NOT NULL,
+	moves TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	finished_at INTEGER NOT NULL
+);
+CREATE INDEX idx_games_winner ON games(winner);
+CREATE INDEX idx_games_player1 ON games(player1, finished_at);
+CREATE INDEX idx_games_player2 ON games(player2, finished_at);
+
+CREATE TABLE ratings (
+	username TEXT PRIMARY KEY,
+	rating REAL NOT NULL,
+	games INTEGER NOT NULL DEFAULT 0,
+	updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE active_games (
+	id TEXT PRIMARY KEY,
+	state TEXT NOT NULL,
+	moves INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE players (
+	username TEXT PRIMARY KEY,
+	password_hash TEXT NOT NULL DEFAULT '',
+	guest INTEGER NOT NULL DEFAULT 0,
+	created_at INTEGER NOT NULL
+);
+CREATE UNIQUE INDEX idx_players_username_lower ON players(LOWER(username));
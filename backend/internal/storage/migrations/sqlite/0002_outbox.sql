@@ -0,0 +1,6 @@
+CREATE TABLE outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	game_id TEXT NOT NULL,
+	event TEXT NOT NULL,
+	created_at INTEGER NOT NULL
+);
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// OutboxEvent is an encoded analytics event waiting for the relay to publish it. Events are
+// written in the same transaction as the game they describe, so a saved game never loses them.
+type OutboxEvent struct {
+	ID        int64
+	GameID    string
+	Event     json.RawMessage
+	CreatedAt time.Time
+}
+
+func insertOutbox(ctx context.Context, tx pgx.Tx, g FinishedGame) error {
+	for _, event := range g.Events {
+		if _, err := tx.Exec(ctx, `INSERT INTO outbox (game_id, event, created_at) VALUES ($1, $2, $3)`,
+			g.ID, event, g.FinishedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PendingEvents returns up to limit unpublished events, oldest first.
+func (r *Repository) PendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id, game_id, event, created_at FROM outbox ORDER BY id LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		if err := rows.Scan(&e.ID, &e.GameID, &e.Event, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// DeleteEvents removes events once they have been published.
+func (r *Repository) DeleteEvents(ctx context.Context, ids []int64) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, ids)
+	return err
+}
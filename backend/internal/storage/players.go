@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrUsernameTaken  = errors.New("username is already taken")
+	ErrPlayerNotFound = errors.New("player not found")
+)
+
+// Player is a registered account, or a guest account with no password.
+type Player struct {
+	Username     string
+	PasswordHash string // empty for guests
+	Guest        bool
+	CreatedAt    time.Time
+}
+
+// CreatePlayer adds an account. Usernames are unique regardless of case.
+func (r *Repository) CreatePlayer(ctx context.Context, p Player) error {
+	tag, err := r.pool.Exec(ctx, `
+INSERT INTO players (username, password_hash, guest, created_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING;
+`, p.Username, p.PasswordHash, p.Guest, p.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUsernameTaken
+	}
+	return nil
+}
+
+// Player looks up an account by its exact username.
+func (r *Repository) Player(ctx context.Context, username string) (Player, error) {
+	p := Player{Username: username}
+	err := r.pool.QueryRow(ctx, `SELECT password_hash, guest, created_at FROM players WHERE username = $1`, username).
+		Scan(&p.PasswordHash, &p.Guest, &p.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Player{}, ErrPlayerNotFound
+	}
+	return p, err
+}
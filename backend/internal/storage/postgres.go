@@ -3,90 +3,131 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/rating"
 )
 
+// Repository is the Postgres Store.
 type Repository struct {
 	pool *pgxpool.Pool
 }
 
 type FinishedGame struct {
-	ID         string          `json:"id"`
-	Player1    string          `json:"player1"`
-	Player2    string          `json:"player2"`
-	Winner     string          `json:"winner"`
-	Moves      json.RawMessage `json:"moves"`
-	CreatedAt  time.Time       `json:"createdAt"`
-	FinishedAt time.Time       `json:"finishedAt"`
-}
-
-type LeaderboardRow struct {
-	Username string `json:"username"`
-	Wins     int    `json:"wins"`
+	ID            string            `json:"id"`
+	Player1       string            `json:"player1"`
+	Player2       string            `json:"player2"`
+	Winner        string            `json:"winner"`
+	Termination   string            `json:"termination"`             // connect, draw, forfeit, timeout, resign or agreement
+	BotDifficulty string            `json:"botDifficulty,omitempty"` // empty for games between two humans
+	Bot           string            `json:"bot,omitempty"`           // username of the bot player, if any
+	BotRating     float64           `json:"-"`                       // fixed rating the bot is rated at
+	Rules         json.RawMessage   `json:"rules"`                   // board size, connect length and PopOut flag
+	Moves         json.RawMessage   `json:"moves,omitempty"`         // left out of game listings
+	Events        []json.RawMessage `json:"-"`                       // analytics events for the outbox, written on first save
+	CreatedAt     time.Time         `json:"createdAt"`
+	FinishedAt    time.Time         `json:"finishedAt"`
 }
 
-func NewRepository(ctx context.Context, url string) (*Repository, error) {
+// NewRepository connects to Postgres. With migrate set it first applies pending migrations;
+// either way it refuses a database whose schema does not match this binary.
+func NewRepository(ctx context.Context, url string, migrate bool) (*Repository, error) {
 	pool, err := pgxpool.New(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	repo := &Repository{pool: pool}
-	if err := repo.init(ctx); err != nil {
+	if migrate {
+		if _, err := repo.Migrate(ctx); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+	if err := repo.checkSchema(ctx); err != nil {
 		pool.Close()
 		return nil, err
 	}
 	return repo, nil
 }
 
-func (r *Repository) init(ctx context.Context) error {
-	_, err := r.pool.Exec(ctx, `
-CREATE TABLE IF NOT EXISTS games (
-	id TEXT PRIMARY KEY,
-	player1 TEXT,
-	player2 TEXT,
-	winner TEXT,
-	moves JSONB,
-	created_at TIMESTAMPTZ,
-	finished_at TIMESTAMPTZ
-);
-CREATE INDEX IF NOT EXISTS idx_games_winner ON games(winner);
-`)
-	return err
-}
-
+// SaveFinishedGame stores the game and, the first time it is saved, updates both players'
+// ratings in the same transaction. A bot is rated at its fixed BotRating and never re-rated.
+// Its Events go to the outbox in that transaction too, and its in-progress snapshot is removed.
 func (r *Repository) SaveFinishedGame(ctx context.Context, g FinishedGame) error {
-	_, err := r.pool.Exec(ctx, `
-INSERT INTO games (id, player1, player2, winner, moves, created_at, finished_at)
-VALUES ($1,$2,$3,$4,$5,$6,$7)
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+INSERT INTO games (id, player1, player2, winner, termination, bot_difficulty, rules, moves, created_at, finished_at)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
 ON CONFLICT (id) DO NOTHING;
-`, g.ID, g.Player1, g.Player2, g.Winner, g.Moves, g.CreatedAt, g.FinishedAt)
-	return err
+`, g.ID, g.Player1, g.Player2, g.Winner, g.Termination, g.BotDifficulty, g.Rules, g.Moves, g.CreatedAt, g.FinishedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 1 {
+		if err := updateRatings(ctx, tx, g); err != nil {
+			return err
+		}
+		if err := insertOutbox(ctx, tx, g); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM active_games WHERE id = $1`, g.ID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (r *Repository) Leaderboard(ctx context.Context, limit int) ([]LeaderboardRow, error) {
-	rows, err := r.pool.Query(ctx, `
-SELECT winner, COUNT(*) AS wins
-FROM games
-WHERE winner <> '' AND winner IS NOT NULL
-GROUP BY winner
-ORDER BY wins DESC
-LIMIT $1;
-`, limit)
-	if err != nil {
-		return nil, err
+func updateRatings(ctx context.Context, tx pgx.Tx, g FinishedGame) error {
+	players := [2]string{g.Player1, g.Player2}
+	var ratings [2]float64
+	for i, name := range players {
+		if name == g.Bot {
+			ratings[i] = g.BotRating
+			continue
+		}
+		err := tx.QueryRow(ctx, `SELECT rating FROM ratings WHERE username = $1 FOR UPDATE`, name).Scan(&ratings[i])
+		if errors.Is(err, pgx.ErrNoRows) {
+			ratings[i] = rating.Default
+		} else if err != nil {
+			return err
+		}
 	}
-	defer rows.Close()
-	var result []LeaderboardRow
-	for rows.Next() {
-		var row LeaderboardRow
-		if err := rows.Scan(&row.Username, &row.Wins); err != nil {
-			return nil, err
+
+	ratings[0], ratings[1] = rating.Update(ratings[0], ratings[1], gameScore(g))
+
+	for i, name := range players {
+		if name == g.Bot {
+			continue
+		}
+		_, err := tx.Exec(ctx, `
+INSERT INTO ratings (username, rating, games, updated_at)
+VALUES ($1, $2, 1, $3)
+ON CONFLICT (username) DO UPDATE SET rating = EXCLUDED.rating, games = ratings.games + 1, updated_at = EXCLUDED.updated_at;
+`, name, ratings[i], g.FinishedAt)
+		if err != nil {
+			return err
 		}
-		result = append(result, row)
 	}
-	return result, rows.Err()
+	return nil
+}
+
+// Rating returns the player's current rating, or rating.Default before their first game.
+func (r *Repository) Rating(ctx context.Context, username string) (float64, error) {
+	var value float64
+	err := r.pool.QueryRow(ctx, `SELECT rating FROM ratings WHERE username = $1`, username).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return rating.Default, nil
+	}
+	return value, err
 }
 
 func (r *Repository) Close() {
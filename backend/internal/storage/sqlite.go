@@ -0,0 +1,489 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/rating"
+)
+
+// SQLiteStore keeps everything in a single SQLite file, for deployments that ship one binary
+// without a database server. Times are stored as Unix milliseconds and JSON as text.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if needed) the database file at path. Like NewRepository it
+// applies pending migrations when migrate is set and refuses a schema that does not match.
+func NewSQLiteStore(ctx context.Context, path string, migrate bool) (*SQLiteStore, error) {
+	db, err := openSQLite(path, false)
+	if err != nil {
+		return nil, err
+	}
+	store := &SQLiteStore{db: db}
+	if migrate {
+		if _, err := store.Migrate(ctx); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	migrations, err := loadMigrations(dialectSQLite)
+	if err == nil {
+		var current int
+		if current, err = store.SchemaVersion(ctx); err == nil || errors.Is(err, ErrUnversioned) {
+			err = compareSchema(current, len(migrations))
+		}
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// openSQLite opens the file at path. A read-only handle fails if the file does not exist
+// instead of creating it, and leaves the journal mode alone.
+func openSQLite(path string, readOnly bool) (*sql.DB, error) {
+	if readOnly {
+		return sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)")
+	}
+	// Immediate transactions take the write lock up front, so rating updates never interleave.
+	return sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate")
+}
+
+// SchemaVersion returns the newest migration applied to the file, or ErrUnversioned if none
+// ever was. It only reads.
+func (s *SQLiteStore) SchemaVersion(ctx context.Context) (int, error) {
+	var tables int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`).
+		Scan(&tables); err != nil {
+		return 0, err
+	}
+	if tables == 0 {
+		return 0, ErrUnversioned
+	}
+	var version int
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// Migrate applies every pending SQLite migration, each in its own transaction.
+func (s *SQLiteStore) Migrate(ctx context.Context) (int, error) {
+	migrations, err := loadMigrations(dialectSQLite)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at INTEGER NOT NULL
+)`); err != nil {
+		return 0, err
+	}
+	current, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if current > len(migrations) {
+		return 0, ErrSchemaAhead{Database: current, Binary: len(migrations)}
+	}
+	for _, m := range migrations[current:] {
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_version (version, name, applied_at) VALUES (?1, ?2, ?3)`,
+				m.version, m.name, toMillis(time.Now()))
+			return err
+		})
+		if err != nil {
+			return m.version - current - 1, fmt.Errorf("migration %s: %w", m.name, err)
+		}
+	}
+	return len(migrations) - current, nil
+}
+
+func (s *SQLiteStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveFinishedGame stores the game and, the first time it is saved, updates both players'
+// ratings and queues its Events in the same transaction, as Repository.SaveFinishedGame does.
+func (s *SQLiteStore) SaveFinishedGame(ctx context.Context, g FinishedGame) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+INSERT INTO games (id, player1, player2, winner, termination, bot_difficulty, rules, moves, created_at, finished_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)
+ON CONFLICT (id) DO NOTHING`,
+			g.ID, g.Player1, g.Player2, g.Winner, g.Termination, g.BotDifficulty, string(g.Rules), jsonText(g.Moves),
+			toMillis(g.CreatedAt), toMillis(g.FinishedAt))
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 1 {
+			if err := s.updateRatings(ctx, tx, g); err != nil {
+				return err
+			}
+			for _, event := range g.Events {
+				if _, err := tx.ExecContext(ctx, `INSERT INTO outbox (game_id, event, created_at) VALUES (?1, ?2, ?3)`,
+					g.ID, string(event), toMillis(g.FinishedAt)); err != nil {
+					return err
+				}
+			}
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM active_games WHERE id = ?1`, g.ID)
+		return err
+	})
+}
+
+func (s *SQLiteStore) updateRatings(ctx context.Context, tx *sql.Tx, g FinishedGame) error {
+	players := [2]string{g.Player1, g.Player2}
+	var ratings [2]float64
+	for i, name := range players {
+		if name == g.Bot {
+			ratings[i] = g.BotRating
+			continue
+		}
+		err := tx.QueryRowContext(ctx, `SELECT rating FROM ratings WHERE username = ?1`, name).Scan(&ratings[i])
+		if errors.Is(err, sql.ErrNoRows) {
+			ratings[i] = rating.Default
+		} else if err != nil {
+			return err
+		}
+	}
+	ratings[0], ratings[1] = rating.Update(ratings[0], ratings[1], gameScore(g))
+
+	for i, name := range players {
+		if name == g.Bot {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO ratings (username, rating, games, updated_at)
+VALUES (?1, ?2, 1, ?3)
+ON CONFLICT (username) DO UPDATE SET rating = excluded.rating, games = ratings.games + 1, updated_at = excluded.updated_at`,
+			name, ratings[i], toMillis(g.FinishedAt))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Rating(ctx context.Context, username string) (float64, error) {
+	var value float64
+	err := s.db.QueryRowContext(ctx, `SELECT rating FROM ratings WHERE username = ?1`, username).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rating.Default, nil
+	}
+	return value, err
+}
+
+func (s *SQLiteStore) FinishedGame(ctx context.Context, id string) (FinishedGame, error) {
+	g := FinishedGame{ID: id}
+	var rules, moves string
+	var created, finished int64
+	err := s.db.QueryRowContext(ctx, `
+SELECT player1, player2, winner, termination, bot_difficulty, rules, moves, created_at, finished_at
+FROM games WHERE id = ?1`, id).
+		Scan(&g.Player1, &g.Player2, &g.Winner, &g.Termination, &g.BotDifficulty, &rules, &moves, &created, &finished)
+	if errors.Is(err, sql.ErrNoRows) {
+		return FinishedGame{}, ErrGameNotFound
+	}
+	g.Rules, g.Moves = []byte(rules), []byte(moves)
+	g.CreatedAt, g.FinishedAt = fromMillis(created), fromMillis(finished)
+	return g, err
+}
+
+// sqlitePlayerGamesWhere mirrors playerGamesWhere; ?1-?5 are username, opponent, result, from and to.
+const sqlitePlayerGamesWhere = `
+WHERE (player1 = ?1 OR player2 = ?1)
+  AND (?2 = '' OR (player1 = ?1 AND player2 = ?2) OR (player2 = ?1 AND player1 = ?2))
+  AND (?3 = ''
+    OR (?3 = 'win' AND winner = ?1)
+    OR (?3 = 'loss' AND winner NOT IN ('', ?1))
+    OR (?3 = 'draw' AND winner = ''))
+  AND (?4 IS NULL OR finished_at >= ?4)
+  AND (?5 IS NULL OR finished_at < ?5)`
+
+func (s *SQLiteStore) PlayerGames(ctx context.Context, f GameFilter) ([]FinishedGame, int, error) {
+	args := []any{f.Username, f.Opponent, f.Result, optionalMillis(f.From), optionalMillis(f.To)}
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM games`+sqlitePlayerGamesWhere, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, player1, player2, winner, termination, bot_difficulty, rules, created_at, finished_at
+FROM games`+sqlitePlayerGamesWhere+`
+ORDER BY finished_at DESC, id
+LIMIT ?6 OFFSET ?7`, append(args, f.Limit, f.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	result := []FinishedGame{}
+	for rows.Next() {
+		var g FinishedGame
+		var rules string
+		var created, finished int64
+		err := rows.Scan(&g.ID, &g.Player1, &g.Player2, &g.Winner, &g.Termination, &g.BotDifficulty, &rules, &created, &finished)
+		if err != nil {
+			return nil, 0, err
+		}
+		g.Rules = []byte(rules)
+		g.CreatedAt, g.FinishedAt = fromMillis(created), fromMillis(finished)
+		result = append(result, g)
+	}
+	return result, total, rows.Err()
+}
+
+func (s *SQLiteStore) PlayerStats(ctx context.Context, username string) (PlayerStats, error) {
+	stats := PlayerStats{Username: username}
+	var first, second, bot [3]int // games, wins, losses
+	err := s.db.QueryRowContext(ctx, `
+SELECT
+	COUNT(*) FILTER (WHERE player1 = ?1),
+	COUNT(*) FILTER (WHERE player1 = ?1 AND winner = ?1),
+	COUNT(*) FILTER (WHERE player1 = ?1 AND winner NOT IN ('', ?1)),
+	COUNT(*) FILTER (WHERE player2 = ?1),
+	COUNT(*) FILTER (WHERE player2 = ?1 AND winner = ?1),
+	COUNT(*) FILTER (WHERE player2 = ?1 AND winner NOT IN ('', ?1)),
+	COUNT(*) FILTER (WHERE `+botGame+`),
+	COUNT(*) FILTER (WHERE `+botGame+` AND winner = ?1),
+	COUNT(*) FILTER (WHERE `+botGame+` AND winner NOT IN ('', ?1)),
+	COUNT(*) FILTER (WHERE termination = 'forfeit' AND winner NOT IN ('', ?1)),
+	COALESCE(AVG(CASE WHEN json_type(moves) = 'array' THEN json_array_length(moves) ELSE 0 END), 0),
+	COALESCE(AVG((finished_at - created_at) / 1000.0), 0)
+FROM games
+WHERE player1 = ?1 OR player2 = ?1`, username).Scan(&first[0], &first[1], &first[2], &second[0], &second[1], &second[2],
+		&bot[0], &bot[1], &bot[2], &stats.Forfeits, &stats.AverageMoves, &stats.AverageSeconds)
+	if err != nil {
+		return PlayerStats{}, err
+	}
+	stats.AsFirst = newRecord(first[0], first[1], first[2])
+	stats.AsSecond = newRecord(second[0], second[1], second[2])
+	stats.VsBot = newRecord(bot[0], bot[1], bot[2])
+	stats.Record = newRecord(first[0]+second[0], first[1]+second[1], first[2]+second[2])
+
+	err = s.db.QueryRowContext(ctx, `
+SELECT COALESCE(MAX(streak), 0) FROM (
+	SELECT COUNT(*) AS streak FROM (
+		SELECT winner = ?1 AS won,
+			ROW_NUMBER() OVER (ORDER BY finished_at, id)
+			- ROW_NUMBER() OVER (PARTITION BY winner = ?1 ORDER BY finished_at, id) AS run
+		FROM games
+		WHERE player1 = ?1 OR player2 = ?1
+	)
+	WHERE won
+	GROUP BY run
+)`, username).Scan(&stats.LongestWinStreak)
+	if err != nil {
+		return PlayerStats{}, err
+	}
+
+	stats.Rating, err = s.Rating(ctx, username)
+	return stats, err
+}
+
+func (s *SQLiteStore) Leaderboard(ctx context.Context, q LeaderboardQuery) (LeaderboardPage, error) {
+	order, ok := leaderboardOrder[q.Metric]
+	if !ok {
+		return LeaderboardPage{}, ErrBadMetric
+	}
+	ranked := fmt.Sprintf(`
+WITH counted AS (
+	SELECT player1, player2, winner FROM games
+	WHERE (?1 IS NULL OR finished_at >= ?1)
+	  AND NOT (?2 AND `+botGame+`)
+	  AND (?3 = '' OR bot_difficulty = ?3)
+), per_player AS (
+	SELECT player1 AS username, winner FROM counted
+	UNION ALL
+	SELECT player2, winner FROM counted
+), stats AS (
+	SELECT username, COUNT(*) AS games, COUNT(*) FILTER (WHERE winner = username) AS wins
+	FROM per_player
+	WHERE username <> 'bot'
+	GROUP BY username
+	HAVING COUNT(*) >= ?4
+), ranked AS (
+	SELECT s.username, s.wins, s.games, CAST(s.wins AS REAL) / s.games AS win_rate,
+		COALESCE(r.rating, %v) AS rating
+	FROM stats s LEFT JOIN ratings r ON r.username = s.username
+)
+SELECT RANK() OVER (ORDER BY %s) AS rank, username, wins, games, win_rate, rating, COUNT(*) OVER () AS total
+FROM ranked`, rating.Default, order)
+	args := []any{optionalMillis(q.Since), q.ExcludeBots, q.BotDifficulty, max(q.MinGames, 1)}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM (`+ranked+`) ORDER BY rank, username LIMIT ?5 OFFSET ?6`,
+		append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return LeaderboardPage{}, err
+	}
+	defer rows.Close()
+	page := LeaderboardPage{Rows: []LeaderboardRow{}}
+	for rows.Next() {
+		var row LeaderboardRow
+		if err := rows.Scan(&row.Rank, &row.Username, &row.Wins, &row.Games, &row.WinRate, &row.Rating, &page.Total); err != nil {
+			return LeaderboardPage{}, err
+		}
+		page.Rows = append(page.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return LeaderboardPage{}, err
+	}
+
+	if len(page.Rows) == 0 && q.Offset > 0 {
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM (`+ranked+`)`, args...).Scan(&page.Total); err != nil {
+			return LeaderboardPage{}, err
+		}
+	}
+	if q.Username != "" {
+		var self LeaderboardRow
+		var total int
+		err := s.db.QueryRowContext(ctx, `SELECT * FROM (`+ranked+`) WHERE username = ?5`, append(args, q.Username)...).
+			Scan(&self.Rank, &self.Username, &self.Wins, &self.Games, &self.WinRate, &self.Rating, &total)
+		if err == nil {
+			page.Self = &self
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return LeaderboardPage{}, err
+		}
+	}
+	return page, nil
+}
+
+func (s *SQLiteStore) SaveActiveGame(ctx context.Context, g ActiveGame) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO active_games (id, state, moves, updated_at)
+SELECT ?1, ?2, ?3, ?4
+WHERE NOT EXISTS (SELECT 1 FROM games WHERE id = ?1)
+ON CONFLICT (id) DO UPDATE SET state = excluded.state, moves = excluded.moves, updated_at = excluded.updated_at
+WHERE active_games.moves <= excluded.moves`, g.ID, string(g.State), g.Moves, toMillis(g.UpdatedAt))
+	return err
+}
+
+func (s *SQLiteStore) ActiveGames(ctx context.Context) ([]ActiveGame, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, state, moves, updated_at FROM active_games`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []ActiveGame
+	for rows.Next() {
+		var g ActiveGame
+		var state string
+		var updated int64
+		if err := rows.Scan(&g.ID, &state, &g.Moves, &updated); err != nil {
+			return nil, err
+		}
+		g.State, g.UpdatedAt = []byte(state), fromMillis(updated)
+		result = append(result, g)
+	}
+	return result, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteActiveGame(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM active_games WHERE id = ?1`, id)
+	return err
+}
+
+func (s *SQLiteStore) PendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, game_id, event, created_at FROM outbox ORDER BY id LIMIT ?1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		var event string
+		var created int64
+		if err := rows.Scan(&e.ID, &e.GameID, &event, &created); err != nil {
+			return nil, err
+		}
+		e.Event, e.CreatedAt = []byte(event), fromMillis(created)
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteEvents(ctx context.Context, ids []int64) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM outbox WHERE id = ?1`, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) CreatePlayer(ctx context.Context, p Player) error {
+	res, err := s.db.ExecContext(ctx, `
+INSERT INTO players (username, password_hash, guest, created_at)
+VALUES (?1, ?2, ?3, ?4)
+ON CONFLICT DO NOTHING`, p.Username, p.PasswordHash, p.Guest, toMillis(p.CreatedAt))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrUsernameTaken
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Player(ctx context.Context, username string) (Player, error) {
+	p := Player{Username: username}
+	var created int64
+	err := s.db.QueryRowContext(ctx, `SELECT password_hash, guest, created_at FROM players WHERE username = ?1`, username).
+		Scan(&p.PasswordHash, &p.Guest, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Player{}, ErrPlayerNotFound
+	}
+	p.CreatedAt = fromMillis(created)
+	return p, err
+}
+
+func (s *SQLiteStore) Close() {
+	s.db.Close()
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixMilli()
+}
+
+func fromMillis(ms int64) time.Time {
+	return time.UnixMilli(ms).UTC()
+}
+
+func optionalMillis(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return toMillis(t)
+}
+
+// jsonText stores a missing JSON document as null rather than an empty string.
+func jsonText(data []byte) string {
+	if len(data) == 0 {
+		return "null"
+	}
+	return string(data)
+}
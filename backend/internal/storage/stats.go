@@ -0,0 +1,92 @@
+package storage
+
+import "context"
+
+// Record is a win/loss/draw tally over some subset of a player's games.
+type Record struct {
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	Draws   int     `json:"draws"`
+	WinRate float64 `json:"winRate"` // wins per game, 0 without games
+}
+
+func newRecord(games, wins, losses int) Record {
+	rec := Record{Games: games, Wins: wins, Losses: losses, Draws: games - wins - losses}
+	if games > 0 {
+		rec.WinRate = float64(wins) / float64(games)
+	}
+	return rec
+}
+
+// PlayerStats summarizes every finished game of a player.
+type PlayerStats struct {
+	Username string  `json:"username"`
+	Rating   float64 `json:"rating"`
+	Record
+	// Forfeits counts games the player lost by not reconnecting in time.
+	Forfeits int    `json:"forfeits"`
+	AsFirst  Record `json:"asFirst"`  // games where the player moved first
+	AsSecond Record `json:"asSecond"` // games where the opponent moved first
+	VsBot    Record `json:"vsBot"`
+	// AverageMoves and AverageSeconds describe game length, counting both players' moves.
+	AverageMoves     float64 `json:"averageMoves"`
+	AverageSeconds   float64 `json:"averageSeconds"`
+	LongestWinStreak int     `json:"longestWinStreak"`
+}
+
+// PlayerStats computes the player's statistics from the games table.
+func (r *Repository) PlayerStats(ctx context.Context, username string) (PlayerStats, error) {
+	stats := PlayerStats{Username: username}
+	var first, second, bot [3]int // games, wins, losses
+	err := r.pool.QueryRow(ctx, `
+SELECT
+	COUNT(*) FILTER (WHERE player1 = $1),
+	COUNT(*) FILTER (WHERE player1 = $1 AND winner = $1),
+	COUNT(*) FILTER (WHERE player1 = $1 AND COALESCE(winner, '') NOT IN ('', $1)),
+	COUNT(*) FILTER (WHERE player2 = $1),
+	COUNT(*) FILTER (WHERE player2 = $1 AND winner = $1),
+	COUNT(*) FILTER (WHERE player2 = $1 AND COALESCE(winner, '') NOT IN ('', $1)),
+	COUNT(*) FILTER (WHERE `+botGame+`),
+	COUNT(*) FILTER (WHERE `+botGame+` AND winner = $1),
+	COUNT(*) FILTER (WHERE `+botGame+` AND COALESCE(winner, '') NOT IN ('', $1)),
+	COUNT(*) FILTER (WHERE termination = 'forfeit' AND COALESCE(winner, '') NOT IN ('', $1)),
+	COALESCE(AVG(CASE WHEN jsonb_typeof(moves) = 'array' THEN jsonb_array_length(moves) ELSE 0 END), 0),
+	COALESCE(AVG(EXTRACT(EPOCH FROM finished_at - created_at)), 0)
+FROM games
+WHERE player1 = $1 OR player2 = $1
+`, username).Scan(&first[0], &first[1], &first[2], &second[0], &second[1], &second[2], &bot[0], &bot[1], &bot[2],
+		&stats.Forfeits, &stats.AverageMoves, &stats.AverageSeconds)
+	if err != nil {
+		return PlayerStats{}, err
+	}
+	stats.AsFirst = newRecord(first[0], first[1], first[2])
+	stats.AsSecond = newRecord(second[0], second[1], second[2])
+	stats.VsBot = newRecord(bot[0], bot[1], bot[2])
+	stats.Record = newRecord(first[0]+second[0], first[1]+second[1], first[2]+second[2])
+
+	// Consecutive wins share the same difference between their overall position and their
+	// position among wins.
+	err = r.pool.QueryRow(ctx, `
+SELECT COALESCE(MAX(streak), 0) FROM (
+	SELECT COUNT(*) AS streak FROM (
+		SELECT COALESCE(winner, '') = $1 AS won,
+			ROW_NUMBER() OVER (ORDER BY finished_at, id)
+			- ROW_NUMBER() OVER (PARTITION BY COALESCE(winner, '') = $1 ORDER BY finished_at, id) AS run
+		FROM games
+		WHERE player1 = $1 OR player2 = $1
+	) ordered
+	WHERE won
+	GROUP BY run
+) runs
+`, username).Scan(&stats.LongestWinStreak)
+	if err != nil {
+		return PlayerStats{}, err
+	}
+
+	stats.Rating, err = r.Rating(ctx, username)
+	return stats, err
+}
+
+// botGame matches games against the bot, including ones saved before difficulties were recorded.
+const botGame = `(bot_difficulty <> '' OR 'bot' IN (player1, player2))`
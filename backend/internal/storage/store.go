@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// Store is everything the server keeps between games. Repository stores it in Postgres,
+// SQLiteStore in a single local file for one-binary deployments, and MemoryStore in process
+// memory for tests and throwaway local runs.
+type Store interface {
+	SaveFinishedGame(ctx context.Context, g FinishedGame) error
+	FinishedGame(ctx context.Context, id string) (FinishedGame, error)
+	PlayerGames(ctx context.Context, f GameFilter) ([]FinishedGame, int, error)
+	PlayerStats(ctx context.Context, username string) (PlayerStats, error)
+	Rating(ctx context.Context, username string) (float64, error)
+	Leaderboard(ctx context.Context, q LeaderboardQuery) (LeaderboardPage, error)
+
+	SaveActiveGame(ctx context.Context, g ActiveGame) error
+	ActiveGames(ctx context.Context) ([]ActiveGame, error)
+	DeleteActiveGame(ctx context.Context, id string) error
+
+	PendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error)
+	DeleteEvents(ctx context.Context, ids []int64) error
+
+	CreatePlayer(ctx context.Context, p Player) error
+	Player(ctx context.Context, username string) (Player, error)
+
+	Close()
+}
+
+var (
+	_ Store = (*Repository)(nil)
+	_ Store = (*SQLiteStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// Open returns the store named by kind: "postgres" at postgresURL, "sqlite" in the file at
+// sqlitePath, or "memory". migrate applies pending schema migrations to the SQL stores.
+func Open(ctx context.Context, kind, postgresURL, sqlitePath string, migrate bool) (Store, error) {
+	switch kind {
+	case "postgres", "":
+		return NewRepository(ctx, postgresURL, migrate)
+	case "sqlite":
+		return NewSQLiteStore(ctx, sqlitePath, migrate)
+	case "memory":
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown storage %q: use postgres, sqlite or memory", kind)
+}
+
+// gameScore is player one's score in g for rating purposes: 1 for a win, 0.5 for a draw, 0 for a loss.
+func gameScore(g FinishedGame) float64 {
+	switch g.Winner {
+	case g.Player1:
+		return 1
+	case g.Player2:
+		return 0
+	}
+	return 0.5
+}
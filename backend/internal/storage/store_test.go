@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rishirajmaheshwari/4-in-a-row/internal/rating"
+)
+
+// The same cases run against every store that needs no server, so MemoryStore keeps answering
+// the way the SQL stores do.
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store { return NewMemoryStore() })
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		store, err := NewSQLiteStore(context.Background(), filepath.Join(t.TempDir(), "game.db"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(store.Close)
+		return store
+	})
+}
+
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// finished builds a game that ended hours after epoch and lasted minutes, with n moves.
+func finished(id, p1, p2, winner, termination string, hours, minutes, n int) FinishedGame {
+	moves := make([]map[string]int, n)
+	for i := range moves {
+		moves[i] = map[string]int{"column": i % 7}
+	}
+	movesJSON, _ := json.Marshal(moves)
+	end := epoch.Add(time.Duration(hours) * time.Hour)
+	return FinishedGame{
+		ID:          id,
+		Player1:     p1,
+		Player2:     p2,
+		Winner:      winner,
+		Termination: termination,
+		Rules:       json.RawMessage(`{"rows":6,"columns":7,"connect":4}`),
+		Moves:       movesJSON,
+		CreatedAt:   end.Add(-time.Duration(minutes) * time.Minute),
+		FinishedAt:  end,
+	}
+}
+
+// fixture returns the games the cases below query, in the order they finished. alice plays
+// g1-g5: a win, a win against the bot, a loss, a draw and a forfeit loss.
+func fixture() []FinishedGame {
+	vsBot := finished("g2", "alice", "bot", "alice", "connect", 2, 2, 9)
+	vsBot.Bot, vsBot.BotDifficulty, vsBot.BotRating = "bot", "expert", 1700
+	return []FinishedGame{
+		finished("g1", "alice", "bob", "alice", "connect", 1, 1, 7),
+		vsBot,
+		finished("g3", "bob", "alice", "bob", "connect", 3, 3, 10),
+		finished("g4", "carol", "alice", "", "draw", 4, 4, 42),
+		finished("g5", "alice", "carol", "carol", "forfeit", 5, 5, 12),
+		finished("g6", "dave", "bob", "dave", "connect", 6, 6, 8),
+	}
+}
+
+func seed(t *testing.T, store Store) {
+	t.Helper()
+	for _, g := range fixture() {
+		if err := store.SaveFinishedGame(context.Background(), g); err != nil {
+			t.Fatalf("save %s: %v", g.ID, err)
+		}
+	}
+}
+
+func testStore(t *testing.T, newStore func(t *testing.T) Store) {
+	t.Run("stats", func(t *testing.T) { testStats(t, newStore(t)) })
+	t.Run("history", func(t *testing.T) { testHistory(t, newStore(t)) })
+	t.Run("leaderboard", func(t *testing.T) { testLeaderboard(t, newStore(t)) })
+	t.Run("outbox", func(t *testing.T) { testOutbox(t, newStore(t)) })
+	t.Run("players", func(t *testing.T) { testPlayers(t, newStore(t)) })
+}
+
+func testStats(t *testing.T, store Store) {
+	ctx := context.Background()
+	seed(t, store)
+	stats, err := store.PlayerStats(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := store.Rating(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current == rating.Default {
+		t.Errorf("rating still %v after five games", current)
+	}
+	want := PlayerStats{
+		Username:         "alice",
+		Rating:           current,
+		Record:           Record{Games: 5, Wins: 2, Losses: 2, Draws: 1, WinRate: 0.4},
+		Forfeits:         1,
+		AsFirst:          Record{Games: 3, Wins: 2, Losses: 1, WinRate: 2.0 / 3},
+		AsSecond:         Record{Games: 2, Losses: 1, Draws: 1},
+		VsBot:            Record{Games: 1, Wins: 1, WinRate: 1},
+		AverageMoves:     16,
+		AverageSeconds:   180,
+		LongestWinStreak: 2,
+	}
+	if stats != want {
+		t.Errorf("got  %+v\nwant %+v", stats, want)
+	}
+
+	empty, err := store.PlayerStats(ctx, "nobody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (PlayerStats{Username: "nobody", Rating: rating.Default}); empty != want {
+		t.Errorf("player without games: got %+v", empty)
+	}
+}
+
+func testHistory(t *testing.T, store Store) {
+	ctx := context.Background()
+	seed(t, store)
+	cases := []struct {
+		name  string
+		f     GameFilter
+		want  []string
+		total int
+	}{
+		{"all", GameFilter{}, []string{"g5", "g4", "g3", "g2", "g1"}, 5},
+		{"opponent", GameFilter{Opponent: "bob"}, []string{"g3", "g1"}, 2},
+		{"wins", GameFilter{Result: ResultWin}, []string{"g2", "g1"}, 2},
+		{"losses", GameFilter{Result: ResultLoss}, []string{"g5", "g3"}, 2},
+		{"draws", GameFilter{Result: ResultDraw}, []string{"g4"}, 1},
+		{"from and to", GameFilter{From: epoch.Add(2 * time.Hour), To: epoch.Add(4 * time.Hour)}, []string{"g3", "g2"}, 2},
+		{"page", GameFilter{Limit: 2, Offset: 2}, []string{"g3", "g2"}, 5},
+		{"past the last page", GameFilter{Limit: 2, Offset: 10}, nil, 5},
+	}
+	for _, tc := range cases {
+		f := tc.f
+		f.Username = "alice"
+		if f.Limit == 0 {
+			f.Limit = 20
+		}
+		games, total, err := store.PlayerGames(ctx, f)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var ids []string
+		for _, g := range games {
+			ids = append(ids, g.ID)
+			if len(g.Moves) != 0 {
+				t.Errorf("%s: %s listed with its moves", tc.name, g.ID)
+			}
+		}
+		if !reflect.DeepEqual(ids, tc.want) || total != tc.total {
+			t.Errorf("%s: got %v of %d, want %v of %d", tc.name, ids, total, tc.want, tc.total)
+		}
+	}
+
+	g, err := store.FinishedGame(ctx, "g4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var moves []json.RawMessage
+	if err := json.Unmarshal(g.Moves, &moves); err != nil || len(moves) != 42 {
+		t.Errorf("g4 moves: %d, %v", len(moves), err)
+	}
+	if _, err := store.FinishedGame(ctx, "missing"); !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("missing game: got %v", err)
+	}
+}
+
+func testLeaderboard(t *testing.T, store Store) {
+	ctx := context.Background()
+	seed(t, store)
+	// ranks renders rows as "rank:username" for compact comparison.
+	ranks := func(rows []LeaderboardRow) string {
+		var out []string
+		for _, row := range rows {
+			out = append(out, fmt.Sprintf("%d:%s", row.Rank, row.Username))
+		}
+		return strings.Join(out, " ")
+	}
+	cases := []struct {
+		name  string
+		q     LeaderboardQuery
+		want  string
+		total int
+		self  string // "rank:username" of q.Username, empty when unranked
+	}{
+		{"wins share ranks", LeaderboardQuery{Metric: MetricWins, Username: "dave"}, "1:alice 2:bob 2:carol 2:dave", 4, "2:dave"},
+		{"page keeps the rank", LeaderboardQuery{Metric: MetricWins, Limit: 2, Offset: 1, Username: "dave"}, "2:bob 2:carol", 4, "2:dave"},
+		{"past the last page", LeaderboardQuery{Metric: MetricWins, Limit: 2, Offset: 10}, "", 4, ""},
+		{"win rate", LeaderboardQuery{Metric: MetricWinRate}, "1:dave 2:carol 3:alice 4:bob", 4, ""},
+		{"without bots everyone ties", LeaderboardQuery{Metric: MetricWins, ExcludeBots: true, Username: "alice"}, "1:alice 1:bob 1:carol 1:dave", 4, "1:alice"},
+		{"bot difficulty", LeaderboardQuery{Metric: MetricWins, BotDifficulty: "expert"}, "1:alice", 1, ""},
+		{"minimum games", LeaderboardQuery{Metric: MetricWins, MinGames: 3}, "1:alice 2:bob", 2, ""},
+		{"since", LeaderboardQuery{Metric: MetricWins, Since: epoch.Add(5 * time.Hour)}, "1:carol 1:dave 3:alice 3:bob", 4, ""},
+		{"bots are not ranked", LeaderboardQuery{Metric: MetricWins, Username: "bot"}, "1:alice 2:bob 2:carol 2:dave", 4, ""},
+		{"unknown player", LeaderboardQuery{Metric: MetricWins, Username: "erin"}, "1:alice 2:bob 2:carol 2:dave", 4, ""},
+	}
+	for _, tc := range cases {
+		q := tc.q
+		if q.Limit == 0 {
+			q.Limit = 20
+		}
+		page, err := store.Leaderboard(ctx, q)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := ranks(page.Rows); got != tc.want || page.Total != tc.total {
+			t.Errorf("%s: got %q of %d, want %q of %d", tc.name, got, page.Total, tc.want, tc.total)
+		}
+		self := ""
+		if page.Self != nil {
+			self = ranks([]LeaderboardRow{*page.Self})
+		}
+		if self != tc.self {
+			t.Errorf("%s: self %q, want %q", tc.name, self, tc.self)
+		}
+	}
+
+	if _, err := store.Leaderboard(ctx, LeaderboardQuery{Metric: "elo", Limit: 20}); !errors.Is(err, ErrBadMetric) {
+		t.Errorf("unknown metric: got %v", err)
+	}
+}
+
+func testOutbox(t *testing.T, store Store) {
+	ctx := context.Background()
+	g := fixture()[0]
+	g.Events = []json.RawMessage{json.RawMessage(`{"type":"finished"}`), json.RawMessage(`{"type":"rematch"}`)}
+	if err := store.SaveFinishedGame(ctx, g); err != nil {
+		t.Fatal(err)
+	}
+	first, err := store.Rating(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A retried save must not queue the events or rate the game twice.
+	if err := store.SaveFinishedGame(ctx, g); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := store.Rating(ctx, "alice"); again != first {
+		t.Errorf("rating moved from %v to %v on re-save", first, again)
+	}
+	pending, err := store.PendingEvents(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("got %d pending events, want 2", len(pending))
+	}
+	for _, e := range pending {
+		if e.GameID != g.ID {
+			t.Errorf("event %d belongs to %q", e.ID, e.GameID)
+		}
+	}
+	if pending[0].ID >= pending[1].ID {
+		t.Errorf("events out of order: %d, %d", pending[0].ID, pending[1].ID)
+	}
+	if limited, _ := store.PendingEvents(ctx, 1); len(limited) != 1 || limited[0].ID != pending[0].ID {
+		t.Errorf("limit 1: got %+v", limited)
+	}
+	if err := store.DeleteEvents(ctx, []int64{pending[0].ID}); err != nil {
+		t.Fatal(err)
+	}
+	left, err := store.PendingEvents(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].ID != pending[1].ID {
+		t.Errorf("after delete: got %+v", left)
+	}
+}
+
+func testPlayers(t *testing.T, store Store) {
+	ctx := context.Background()
+	created := epoch.Add(time.Hour)
+	if err := store.CreatePlayer(ctx, Player{Username: "Alice", PasswordHash: "hash", CreatedAt: created}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Alice", "alice", "ALICE"} {
+		if err := store.CreatePlayer(ctx, Player{Username: name, Guest: true, CreatedAt: created}); !errors.Is(err, ErrUsernameTaken) {
+			t.Errorf("create %q: got %v, want ErrUsernameTaken", name, err)
+		}
+	}
+	p, err := store.Player(ctx, "Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Username != "Alice" || p.PasswordHash != "hash" || p.Guest || !p.CreatedAt.Equal(created) {
+		t.Errorf("got %+v", p)
+	}
+	// Lookups are exact; only uniqueness ignores case.
+	if _, err := store.Player(ctx, "alice"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("lookup with other case: got %v", err)
+	}
+	if _, err := store.Player(ctx, "bob"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("unknown player: got %v", err)
+	}
+}
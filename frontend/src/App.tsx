@@ -9,7 +9,10 @@ function App() {
   const {
     username,
     setUsername,
+    password,
+    setPassword,
     connected,
+    signedInAs,
     status,
     board,
     gameId,
@@ -33,8 +36,11 @@ function App() {
           <Controls 
             username={username}
             setUsername={setUsername}
+            password={password}
+            setPassword={setPassword}
             onConnect={connect}
             connected={connected}
+            signedInAs={signedInAs}
           />
           <Status message={status} winner={winner} />
           <Board 
@@ -1,31 +1,53 @@
 import React from 'react'
+import type { AuthMode } from '../hooks/useGame'
 
 type ControlsProps = {
   username: string
   setUsername: (name: string) => void
-  onConnect: () => void
+  password: string
+  setPassword: (password: string) => void
+  onConnect: (mode: AuthMode) => void
   connected: boolean
+  // signedInAs is the account of the stored session, which Play reuses without a password.
+  signedInAs: string
 }
 
-export const Controls: React.FC<ControlsProps> = ({ username, setUsername, onConnect, connected }) => {
+export const Controls: React.FC<ControlsProps> = ({ username, setUsername, password, setPassword, onConnect, connected, signedInAs }) => {
+  const name = username.trim()
+  const resumes = !!signedInAs && (!name || name === signedInAs)
   return (
     <div className="controls">
       <div className="input-group">
         <input
           type="text"
-          placeholder="Enter Username"
+          placeholder="Username (blank for guest)"
           value={username}
           onChange={(e) => setUsername(e.target.value)}
           disabled={connected}
-          maxLength={15}
+          maxLength={20}
+        />
+        <input
+          type="password"
+          placeholder="Password"
+          value={password}
+          onChange={(e) => setPassword(e.target.value)}
+          disabled={connected || !name || resumes}
         />
         <button 
-          onClick={onConnect} 
-          disabled={connected || !username.trim()}
+          onClick={() => onConnect('play')} 
+          disabled={connected || (!!name && !password && !resumes)}
           className={connected ? 'connected' : ''}
         >
-          {connected ? 'Connected' : 'Play Now'}
+          {connected ? 'Connected' : resumes ? `Play as ${signedInAs}` : name ? 'Log In & Play' : 'Play as Guest'}
         </button>
+        {!connected && (
+          <button
+            onClick={() => onConnect('register')}
+            disabled={!name || !password}
+          >
+            Register
+          </button>
+        )}
       </div>
     </div>
   )
@@ -13,9 +13,9 @@ export const Leaderboard: React.FC<LeaderboardProps> = ({ data }) => {
         {data.length === 0 ? (
           <div className="empty-state">No records yet</div>
         ) : (
-          data.map((entry, index) => (
+          data.map((entry) => (
             <div key={entry.username} className="leaderboard-item">
-              <span className="rank">#{index + 1}</span>
+              <span className="rank">#{entry.rank}</span>
               <span className="name">{entry.username}</span>
               <span className="score">{entry.wins} wins</span>
             </div>
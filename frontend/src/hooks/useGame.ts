@@ -18,10 +18,42 @@ export type GameState = {
 }
 
 export type LeaderboardEntry = {
+  rank: number
   username: string
   wins: number
 }
 
+export type Session = {
+  token: string
+  username: string
+  guest?: boolean
+  expiresAt: string
+}
+
+export type AuthMode = 'play' | 'register'
+
+const SESSION_KEY = 'connect4.session'
+
+// loadSession returns the stored session while its token is still valid.
+const loadSession = (): Session | null => {
+  try {
+    const stored: Session | null = JSON.parse(localStorage.getItem(SESSION_KEY) || 'null')
+    if (stored && new Date(stored.expiresAt).getTime() > Date.now()) return stored
+  } catch {
+    // Unreadable entries are replaced on the next sign-in.
+  }
+  localStorage.removeItem(SESSION_KEY)
+  return null
+}
+
+const saveSession = (session: Session | null) => {
+  if (session) {
+    localStorage.setItem(SESSION_KEY, JSON.stringify(session))
+  } else {
+    localStorage.removeItem(SESSION_KEY)
+  }
+}
+
 export const useGame = () => {
   const DEFAULT_BACKEND_ORIGIN = 'https://connect-4-backend-4usc.onrender.com'
 
@@ -39,30 +71,69 @@ export const useGame = () => {
   }
 
   const [username, setUsername] = useState('')
+  const [password, setPassword] = useState('')
   const [connected, setConnected] = useState(false)
-  const [status, setStatus] = useState('Enter a username to start')
+  const [status, setStatus] = useState('Sign in or play as a guest')
   const [board, setBoard] = useState<number[][]>(Array.from({ length: 6 }, () => Array(7).fill(0)))
   const [gameId, setGameId] = useState('')
   const [yourTurn, setYourTurn] = useState(false)
   const [opponent, setOpponent] = useState('')
   const [winner, setWinner] = useState('')
   const [leaderboard, setLeaderboard] = useState<LeaderboardEntry[]>([])
+  // The session is kept across reconnects and reloads so a dropped player, guests included,
+  // can reclaim their seat with the same token.
+  const [session, setSession] = useState<Session | null>(loadSession)
 
   const socketRef = useRef<WebSocket | null>(null)
 
-  const connect = useCallback(() => {
-    if (!username) return
+  // Registers, logs in, or (with no username) starts a guest session.
+  const signIn = async (mode: AuthMode): Promise<Session | null> => {
+    const path = mode === 'register' ? '/auth/register' : username ? '/auth/login' : '/auth/guest'
+    const res = await fetch(apiUrl(path), {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ username, password })
+    })
+    if (!res.ok) {
+      setStatus((await res.text()).trim() || 'Sign in failed')
+      return null
+    }
+    return res.json()
+  }
+
+  // sessionFor reuses the current session when it matches what the form asks for: the same
+  // account, or a blank username for whoever is signed in. Otherwise it signs in again.
+  const sessionFor = async (mode: AuthMode): Promise<Session | null> => {
+    const current = session && loadSession()
+    const name = username.trim()
+    if (mode === 'play' && current && (!name || name === current.username)) {
+      return current
+    }
+    const fresh = await signIn(mode)
+    if (fresh) {
+      saveSession(fresh)
+      setSession(fresh)
+    }
+    return fresh
+  }
+
+  const connect = useCallback(async (mode: AuthMode = 'play') => {
+    const active = await sessionFor(mode)
+    if (!active) return
+    const me = active.username
+    setPassword('')
 
     // Close existing connection if any
     if (socketRef.current) {
       socketRef.current.close()
     }
 
-    const protocol = location.protocol === 'https:' ? 'wss:' : 'ws:'
-    const ws = new WebSocket(wsUrl(`/ws?username=${encodeURIComponent(username)}`))
+    const ws = new WebSocket(wsUrl(`/ws?token=${encodeURIComponent(active.token)}`))
     socketRef.current = ws
+    let opened = false
 
     ws.onopen = () => {
+      opened = true
       setConnected(true)
       setStatus('Waiting for match...')
     }
@@ -87,7 +158,7 @@ export const useGame = () => {
             setStatus('Draw')
           } else if (state.done && state.winner) {
             const winnerName = state.players[state.winner - 1].username
-            setStatus(winnerName === username ? 'You won!' : `${winnerName} won`)
+            setStatus(winnerName === me ? 'You won!' : `${winnerName} won`)
             // Refresh leaderboard on game end
             fetchLeaderboard()
           } else {
@@ -102,6 +173,11 @@ export const useGame = () => {
     }
 
     ws.onclose = () => {
+      if (!opened) {
+        // The server refused the token (expired or revoked); sign in afresh next time.
+        saveSession(null)
+        setSession(null)
+      }
       setConnected(false)
       setStatus('Disconnected')
       setBoard(Array.from({ length: 6 }, () => Array(7).fill(0)))
@@ -109,7 +185,7 @@ export const useGame = () => {
       setOpponent('')
       setWinner('')
     }
-  }, [username])
+  }, [username, password, session])
 
   const sendMove = useCallback((col: number) => {
     if (!socketRef.current || socketRef.current.readyState !== WebSocket.OPEN) return
@@ -126,7 +202,7 @@ export const useGame = () => {
       const res = await fetch(apiUrl('/leaderboard'))
       if (res.ok) {
         const data = await res.json()
-        setLeaderboard(data)
+        setLeaderboard(data.rows)
       }
     } catch (err) {
       console.error(err)
@@ -145,7 +221,10 @@ export const useGame = () => {
   return {
     username,
     setUsername,
+    password,
+    setPassword,
     connected,
+    signedInAs: session?.username || '',
     status,
     board,
     gameId,